@@ -0,0 +1,337 @@
+package chess
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"strings"
+	"time"
+)
+
+//Judgement how much a move threw away
+type Judgement int
+
+const (
+	//JudgementNone the move was fine
+	JudgementNone Judgement = iota
+	//JudgementInaccuracy the move lost a little
+	JudgementInaccuracy
+	//JudgementMistake the move lost about a pawn
+	JudgementMistake
+	//JudgementBlunder the move lost a piece or the game
+	JudgementBlunder
+)
+
+const (
+	inaccuracyLoss = 50
+	mistakeLoss    = 100
+	blunderLoss    = 300
+	//evaluationCap mates and won positions count as this many centipawns
+	evaluationCap = 1000
+)
+
+func (j Judgement) String() string {
+	switch j {
+	case JudgementInaccuracy:
+		return "inaccuracy"
+	case JudgementMistake:
+		return "mistake"
+	case JudgementBlunder:
+		return "blunder"
+	}
+
+	return "none"
+}
+
+//Symbol the annotation added after the move
+func (j Judgement) Symbol() string {
+	switch j {
+	case JudgementInaccuracy:
+		return "?!"
+	case JudgementMistake:
+		return "?"
+	case JudgementBlunder:
+		return "??"
+	}
+
+	return ""
+}
+
+//judge returns the judgement for a move losing the centipawns
+func judge(loss int) Judgement {
+	switch {
+	case loss >= blunderLoss:
+		return JudgementBlunder
+	case loss >= mistakeLoss:
+		return JudgementMistake
+	case loss >= inaccuracyLoss:
+		return JudgementInaccuracy
+	}
+
+	return JudgementNone
+}
+
+//Evaluation an engine's opinion of a position
+type Evaluation struct {
+	//Best the best move or an empty move when the game is over
+	Best Move
+	//Score centipawns for white
+	Score int
+	//Mate moves until white mates, negative when black mates
+	Mate int
+}
+
+//capped returns the score for white with mates counted as winning
+func (e Evaluation) capped() int {
+	switch {
+	case e.Mate > 0:
+		return evaluationCap
+	case e.Mate < 0:
+		return -evaluationCap
+	case e.Score > evaluationCap:
+		return evaluationCap
+	case e.Score < -evaluationCap:
+		return -evaluationCap
+	}
+
+	return e.Score
+}
+
+func (e Evaluation) String() string {
+	if e.Mate != 0 {
+		return fmt.Sprintf("#%d", e.Mate)
+	}
+
+	return fmt.Sprintf("%+.2f", float64(e.Score)/100)
+}
+
+//Analyser evaluates the current position of a game
+type Analyser func(g *Game) (Evaluation, error)
+
+//EngineAnalyser evaluates positions with the built in engine
+func EngineAnalyser(limits SearchLimits) Analyser {
+	return func(g *Game) (Evaluation, error) {
+		result, err := g.Search(limits)
+		if err != nil {
+			return Evaluation{}, err
+		}
+
+		eval := Evaluation{Best: result.Move, Score: result.Score, Mate: result.MateIn()}
+		if g.Turn == SideBlack {
+			eval.Score, eval.Mate = -eval.Score, -eval.Mate
+		}
+
+		return eval, nil
+	}
+}
+
+//DefaultAnalyser the built in engine searching each position to the same depth
+//so evaluations can be compared
+var DefaultAnalyser = EngineAnalyser(SearchLimits{Depth: 5, Time: 2 * time.Second})
+
+//MoveAnalysis what the engine thought of a single move
+type MoveAnalysis struct {
+	Move Move
+	SAN  string
+	Side SideType
+	//MoveNumber the full move number the move was made on
+	MoveNumber int
+	//Before the evaluation before the move with the best move
+	Before Evaluation
+	//After the evaluation after the move
+	After Evaluation
+	//Loss centipawns the move lost for the player
+	Loss      int
+	Judgement Judgement
+	//BestSAN the engine's move in SAN when the move was judged
+	BestSAN string
+}
+
+//Label the move number and move like 12... Qxb2
+func (m MoveAnalysis) Label() string {
+	if m.Side == SideBlack {
+		return fmt.Sprintf("%d... %s", m.MoveNumber, m.SAN)
+	}
+
+	return fmt.Sprintf("%d. %s", m.MoveNumber, m.SAN)
+}
+
+//Analysis an engine's review of every move in a game
+type Analysis struct {
+	Moves []MoveAnalysis
+	//Start the evaluation of the starting position
+	Start Evaluation
+}
+
+//finalEvaluation scores a position the game ended in
+func finalEvaluation(g *Game) Evaluation {
+	switch g.Result.Outcome {
+	case OutcomeWhiteWins:
+		return Evaluation{Mate: 1}
+	case OutcomeBlackWins:
+		return Evaluation{Mate: -1}
+	}
+
+	return Evaluation{}
+}
+
+//Analyse runs the analyser over every position in the game
+func (g *Game) Analyse(analyser Analyser) (*Analysis, error) {
+	replay := *g
+	replay.Moves = nil
+	replay.MoveTimes = nil
+	replay.Result = Result{}
+	replay.TimeControl = TimeControl{}
+	replay.Turn = g.initialTurn()
+	replay.ProcessMoves()
+
+	before, err := analyser(&replay)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Analysis{Start: before}
+	for _, mv := range g.Moves {
+		move := MoveAnalysis{
+			Move:       mv,
+			SAN:        replay.MoveSAN(mv),
+			Side:       replay.Turn,
+			MoveNumber: replay.fullMove,
+			Before:     before,
+		}
+		bestSAN := ""
+		if before.Best != (Move{}) {
+			bestSAN = replay.MoveSAN(before.Best)
+		}
+
+		replay.MakeMove(mv)
+
+		after := finalEvaluation(&replay)
+		if !replay.Over() {
+			if after, err = analyser(&replay); err != nil {
+				return nil, err
+			}
+		}
+		move.After = after
+
+		move.Loss = before.capped() - after.capped()
+		if move.Side == SideBlack {
+			move.Loss = -move.Loss
+		}
+		if mv != before.Best {
+			move.Judgement = judge(move.Loss)
+		}
+		if move.Judgement != JudgementNone {
+			move.BestSAN = bestSAN
+		}
+
+		result.Moves = append(result.Moves, move)
+		before = after
+	}
+
+	return result, nil
+}
+
+//Count returns how many moves the side made with the judgement
+func (a *Analysis) Count(side SideType, judgement Judgement) int {
+	count := 0
+	for _, mv := range a.Moves {
+		if mv.Side == side && mv.Judgement == judgement {
+			count++
+		}
+	}
+
+	return count
+}
+
+//Annotated returns the move list with judged moves marked and the
+//engine's move in brackets
+func (a *Analysis) Annotated() string {
+	var b strings.Builder
+
+	for i, mv := range a.Moves {
+		switch {
+		case mv.Side == SideWhite:
+			fmt.Fprintf(&b, "%d. ", mv.MoveNumber)
+		case i == 0:
+			fmt.Fprintf(&b, "%d... ", mv.MoveNumber)
+		}
+
+		b.WriteString(mv.SAN + mv.Judgement.Symbol())
+		if mv.Judgement != JudgementNone && mv.BestSAN != "" {
+			fmt.Fprintf(&b, " (%s)", mv.BestSAN)
+		}
+		b.WriteString(" ")
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+const (
+	graphWidth  = 800
+	graphHeight = 240
+)
+
+var (
+	graphBlack = color.RGBA{40, 40, 40, 255}
+	graphWhite = color.RGBA{235, 235, 235, 255}
+	graphLine  = color.RGBA{128, 128, 128, 255}
+	//judgementColors the marker drawn for each judged move
+	judgementColors = map[Judgement]color.RGBA{
+		JudgementInaccuracy: {230, 200, 40, 255},
+		JudgementMistake:    {230, 130, 30, 255},
+		JudgementBlunder:    {210, 40, 40, 255},
+	}
+)
+
+//graphY the row a capped score is drawn at
+func graphY(score int) int {
+	return graphHeight/2 - score*(graphHeight/2)/evaluationCap
+}
+
+//createGraphRaw draws white's advantage after each move, white above the line
+func (a *Analysis) createGraphRaw() image.Image {
+	b := image.Rect(0, 0, graphWidth, graphHeight)
+	graph := image.NewRGBA(b)
+	draw.Draw(graph, b, &image.Uniform{graphBlack}, image.ZP, draw.Src)
+
+	scores := []int{a.Start.capped()}
+	for _, mv := range a.Moves {
+		scores = append(scores, mv.After.capped())
+	}
+
+	step := float64(graphWidth) / float64(len(scores))
+	for i, score := range scores {
+		col := image.Rect(int(float64(i)*step), graphY(score), int(float64(i+1)*step), graphHeight)
+		draw.Draw(graph, col, &image.Uniform{graphWhite}, image.ZP, draw.Src)
+	}
+
+	middle := image.Rect(0, graphHeight/2, graphWidth, graphHeight/2+1)
+	draw.Draw(graph, middle, &image.Uniform{graphLine}, image.ZP, draw.Src)
+
+	for i, mv := range a.Moves {
+		markerColor, ok := judgementColors[mv.Judgement]
+		if !ok {
+			continue
+		}
+
+		x := int((float64(i+1) + 0.5) * step)
+		y := graphY(mv.After.capped())
+		marker := image.Rect(x-3, y-3, x+4, y+4).Intersect(b)
+		draw.Draw(graph, marker, &image.Uniform{markerColor}, image.ZP, draw.Src)
+	}
+
+	return graph
+}
+
+//CreateGraph Creates a png of the evaluation after each move
+func (a *Analysis) CreateGraph() io.Reader {
+	result := &bytes.Buffer{}
+
+	png.Encode(result, a.createGraphRaw())
+	return result
+}
@@ -0,0 +1,98 @@
+package chess
+
+import (
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testAnalyser = EngineAnalyser(SearchLimits{Depth: 4, Time: 5 * time.Second})
+
+func TestAnalyseFindsBlunder(t *testing.T) {
+	game := newTestGame()
+	playSAN(t, &game, "e4 e5 Qh5 Nc6 Bc4 Nf6 Qxf7#")
+
+	analysis, err := game.Analyse(testAnalyser)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(analysis.Moves) != len(game.Moves) {
+		t.Fatalf("analysed %d moves want %d", len(analysis.Moves), len(game.Moves))
+	}
+
+	blunder := analysis.Moves[5]
+	if blunder.SAN != "Nf6" || blunder.Judgement != JudgementBlunder {
+		t.Errorf("%s judged %s want Nf6 blunder", blunder.SAN, blunder.Judgement)
+	}
+	if blunder.BestSAN == "" || blunder.BestSAN == "Nf6" {
+		t.Errorf("blunder best move %q", blunder.BestSAN)
+	}
+	if blunder.Label() != "3... Nf6" {
+		t.Errorf("label %q", blunder.Label())
+	}
+
+	mate := analysis.Moves[6]
+	if mate.Judgement != JudgementNone || mate.After.Mate != 1 {
+		t.Errorf("mate judged %s with %s", mate.Judgement, mate.After)
+	}
+
+	if got := analysis.Count(SideBlack, JudgementBlunder); got != 1 {
+		t.Errorf("black blunders %d want 1", got)
+	}
+	if got := analysis.Count(SideWhite, JudgementBlunder); got != 0 {
+		t.Errorf("white blunders %d want 0", got)
+	}
+
+	annotated := analysis.Annotated()
+	if !strings.HasPrefix(annotated, "1. e4 e5 2. Qh5") || !strings.Contains(annotated, "Nf6?? ("+blunder.BestSAN+")") {
+		t.Errorf("annotated %q", annotated)
+	}
+
+	if game.Result != WinFor(SideWhite, ReasonCheckmate) || len(game.Moves) != 7 {
+		t.Error("analysing changed the game")
+	}
+}
+
+func TestJudge(t *testing.T) {
+	tests := []struct {
+		loss int
+		want Judgement
+	}{
+		{-200, JudgementNone},
+		{49, JudgementNone},
+		{50, JudgementInaccuracy},
+		{100, JudgementMistake},
+		{299, JudgementMistake},
+		{300, JudgementBlunder},
+		{2000, JudgementBlunder},
+	}
+
+	for _, test := range tests {
+		if got := judge(test.loss); got != test.want {
+			t.Errorf("judge(%d) = %s want %s", test.loss, got, test.want)
+		}
+	}
+}
+
+func TestCreateGraph(t *testing.T) {
+	game := newTestGame()
+	playSAN(t, &game, "e4 e5 Nf3")
+
+	analysis, err := game.Analyse(func(g *Game) (Evaluation, error) {
+		return Evaluation{Score: len(g.Moves) * 100}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := png.Decode(analysis.CreateGraph())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size := img.Bounds().Size(); size.X != graphWidth || size.Y != graphHeight {
+		t.Errorf("graph is %v", size)
+	}
+}
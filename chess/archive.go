@@ -0,0 +1,28 @@
+package chess
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+//archiveTimeFormat the time at the start of an archive id
+const archiveTimeFormat = "2006:01:02-15:04:05"
+
+//ArchiveIDPattern matches the archive ids finished games are stored under
+const ArchiveIDPattern = "^\\d{4}:\\d{2}:\\d{2}-\\d{2}:\\d{2}:\\d{2}_[0-9a-z_]+$"
+
+//ArchiveID the archive id of the game when it's archived at the time
+func (g *Game) ArchiveID(at time.Time) string {
+	return fmt.Sprintf("%s_%s", at.Format(archiveTimeFormat), g.ID())
+}
+
+//ArchiveTime the time a game was archived from its archive id
+func ArchiveTime(archiveID string) (time.Time, error) {
+	if len(archiveID) < len(archiveTimeFormat) {
+		return time.Time{}, errors.Errorf("%s isn't an archive id", archiveID)
+	}
+
+	return time.Parse(archiveTimeFormat, archiveID[:len(archiveTimeFormat)])
+}
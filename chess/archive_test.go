@@ -0,0 +1,55 @@
+package chess
+
+import (
+	"image/color"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestArchiveID(t *testing.T) {
+	at := time.Date(2021, 3, 4, 15, 6, 7, 0, time.UTC)
+	game := CreateGame("1", "2", "guild", color.RGBA{}, color.RGBA{})
+	//Games saved before games had their own id use the pair id
+	legacy := CreateGame("123", "456", "789", color.RGBA{}, color.RGBA{})
+	legacy.UID = ""
+
+	re := regexp.MustCompile(ArchiveIDPattern)
+	for _, g := range []Game{game, legacy} {
+		id := g.ArchiveID(at)
+		if !re.MatchString(id) {
+			t.Errorf("%s doesn't match the archive id pattern", id)
+		}
+
+		got, err := ArchiveTime(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(at) {
+			t.Errorf("%s got time %v want %v", id, got, at)
+		}
+	}
+}
+
+func TestArchiveIDPattern(t *testing.T) {
+	re := regexp.MustCompile(ArchiveIDPattern)
+	for _, id := range []string{
+		"1a2b3c4d",
+		"2021:03:04-15:06:07",
+		"2021:03:04 15:06:07_1a2b3c4d",
+		"2021-03-04-15:06:07_1a2b3c4d",
+		"2021:03:04-15:06:07_1a2b3c4d extra",
+	} {
+		if re.MatchString(id) {
+			t.Errorf("%q shouldn't match the archive id pattern", id)
+		}
+	}
+}
+
+func TestArchiveTimeErrors(t *testing.T) {
+	for _, id := range []string{"", "1a2b3c4d", "2021:13:04-15:06:07_1a2b3c4d", "abcd:ef:gh-ij:kl:mn_1a2b3c4d"} {
+		if _, err := ArchiveTime(id); err == nil {
+			t.Errorf("%q: expected an error", id)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package chess
+
+import (
+	"github.com/pkg/errors"
+)
+
+//castlingRights which sides can still castle and which way
+type castlingRights uint8
+
+const (
+	castleWhiteKing castlingRights = 1 << iota
+	castleWhiteQueen
+	castleBlackKing
+	castleBlackQueen
+)
+
+//rookCorners the castling right lost when a corner is moved from or taken
+var rookCorners = map[Postion]castlingRights{
+	{rowHight - 1, rowWidth - 1}: castleWhiteKing,
+	{rowHight - 1, 0}:            castleWhiteQueen,
+	{0, rowWidth - 1}:            castleBlackKing,
+	{0, 0}:                       castleBlackQueen,
+}
+
+//homeRow returns the row the side's pieces start on
+func homeRow(side SideType) int {
+	if side == SideBlack {
+		return 0
+	}
+
+	return rowHight - 1
+}
+
+//castlingRight returns the right needed for a side to castle
+func castlingRight(side SideType, kingSide bool) castlingRights {
+	switch {
+	case side == SideWhite && kingSide:
+		return castleWhiteKing
+	case side == SideWhite:
+		return castleWhiteQueen
+	case kingSide:
+		return castleBlackKing
+	}
+
+	return castleBlackQueen
+}
+
+//isCastling returns true if the move is a king castling move
+func isCastling(mv Move) bool {
+	colMoveD := mv.To.Col - mv.From.Col
+	return mv.From.Row == mv.To.Row && mv.From.Col == 4 &&
+		(colMoveD == 2 || colMoveD == -2)
+}
+
+//castlingRookMove returns the move the rook makes when castling
+func castlingRookMove(mv Move) Move {
+	row := mv.From.Row
+	if mv.To.Col > mv.From.Col {
+		return Move{From: Postion{row, rowWidth - 1}, To: Postion{row, 5}}
+	}
+
+	return Move{From: Postion{row, 0}, To: Postion{row, 3}}
+}
+
+//CastlingMove returns the king move used to castle
+func CastlingMove(side SideType, kingSide bool) Move {
+	row := homeRow(side)
+	mv := Move{From: Postion{row, 4}, To: Postion{row, 2}}
+	if kingSide {
+		mv.To.Col = 6
+	}
+
+	return mv
+}
+
+var (
+	errCastlingMoved   = errors.New("cannot castle after the king or rook has moved")
+	errNoCastlingRook  = errors.New("there is no rook to castle with")
+	errCastlingBlocked = errors.New("cannot castle with pieces in the way")
+	errCastlingCheck   = errors.New("cannot castle out of or through check")
+)
+
+//castlingError returns why the side can't castle that way or nil if it can
+func (p *position) castlingError(side SideType, kingSide bool) error {
+	mv := CastlingMove(side, kingSide)
+	if king := p.board[mv.From.Row][mv.From.Col]; p.castling&castlingRight(side, kingSide) == 0 ||
+		king.Kind != PieceTypeKing || king.Side != side {
+		return errCastlingMoved
+	}
+
+	rookMove := castlingRookMove(mv)
+	if rook := p.board[rookMove.From.Row][rookMove.From.Col]; rook.Kind != PieceTypeRook || rook.Side != side {
+		return errNoCastlingRook
+	}
+
+	low, high := rookMove.From.Col, mv.From.Col
+	if low > high {
+		low, high = high, low
+	}
+	for col := low + 1; col < high; col++ {
+		if p.board[mv.From.Row][col].Kind != PieceTypeEmpty {
+			return errCastlingBlocked
+		}
+	}
+
+	//The king can't castle out of, through or into check
+	for _, col := range []int{mv.From.Col, rookMove.To.Col, mv.To.Col} {
+		if p.attackedBy(Postion{mv.From.Row, col}, side.other()) {
+			return errCastlingCheck
+		}
+	}
+
+	return nil
+}
+
+//validCastling checks the king is allowed to castle
+func (g *Game) validCastling(mv Move) error {
+	king := g.getAt(mv.From)
+	if mv.From.Row != homeRow(king.Side) {
+		return errCastlingMoved
+	}
+
+	return g.castlingError(king.Side, mv.To.Col > mv.From.Col)
+}
+
+//updateCastlingRights removes castling rights once the king or rooks move
+func (g *Game) updateCastlingRights(mv Move) {
+	switch piece := g.getAt(mv.From); {
+	case piece.Kind == PieceTypeKing && piece.Side == SideWhite:
+		g.castling &^= castleWhiteKing | castleWhiteQueen
+	case piece.Kind == PieceTypeKing && piece.Side == SideBlack:
+		g.castling &^= castleBlackKing | castleBlackQueen
+	}
+
+	//Moving from or taking on a corner means that rook can't castle
+	g.castling &^= rookCorners[mv.From] | rookCorners[mv.To]
+}
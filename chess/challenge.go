@@ -0,0 +1,67 @@
+package chess
+
+import (
+	"fmt"
+	"image/color"
+	"time"
+)
+
+//Challenge an invitation to play a game waiting for the target to answer
+type Challenge struct {
+	Challenger string `json:"challenger"`
+	Target     string `json:"target"`
+	GuildID    string `json:"gid"`
+	//Side the challenger's side or SideEmpty for a random side
+	Side        SideType    `json:"side"`
+	TimeControl TimeControl `json:"time_control"`
+	WhiteColor  color.RGBA  `json:"white_color"`
+	BlackColor  color.RGBA  `json:"black_color"`
+	Expires     time.Time   `json:"expires"`
+	//Computer the difficulty when the target is the computer
+	Computer Difficulty `json:"computer,omitempty"`
+	//PGN the loaded game to continue from if there is one
+	PGN string `json:"pgn,omitempty"`
+}
+
+//ChallengeID the id of a challenge from the challenger to the target
+func ChallengeID(guild, challenger, target string) string {
+	return fmt.Sprintf("challenge_%s_%s_%s", guild, challenger, target)
+}
+
+//ID id
+func (c *Challenge) ID() string {
+	return ChallengeID(c.GuildID, c.Challenger, c.Target)
+}
+
+//CreateGame creates the challenged game, randomSide is the challenger's
+//side when they left it up to chance
+func (c *Challenge) CreateGame(randomSide SideType) (Game, error) {
+	side := c.Side
+	if side == SideEmpty {
+		side = randomSide
+	}
+
+	white, black := c.Challenger, c.Target
+	if side == SideBlack {
+		white, black = black, white
+	}
+
+	game := CreateGame(white, black, c.GuildID, c.WhiteColor, c.BlackColor)
+	if c.PGN != "" {
+		var err error
+		game, err = CreateGameFromPGN(white, black, c.GuildID, c.WhiteColor, c.BlackColor, c.PGN)
+		if err != nil {
+			return Game{}, err
+		}
+	}
+	game.TimeControl = c.TimeControl
+	//Only fresh games between people count towards ratings
+	game.Rated = c.Computer == DifficultyNone && c.PGN == ""
+	if side == SideBlack {
+		game.White.Computer = c.Computer
+	} else {
+		game.Black.Computer = c.Computer
+	}
+
+	return game, nil
+}
@@ -0,0 +1,50 @@
+package chess
+
+import "testing"
+
+func TestChallengeCreateGame(t *testing.T) {
+	challenge := Challenge{Challenger: "1", Target: "2", Side: SideBlack}
+
+	game, err := challenge.CreateGame(SideWhite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.White.ID != "2" || game.Black.ID != "1" {
+		t.Errorf("chosen side ignored white %s black %s", game.White.ID, game.Black.ID)
+	}
+
+	challenge.Side = SideEmpty
+	game, _ = challenge.CreateGame(SideWhite)
+	if game.White.ID != "1" || game.Black.ID != "2" {
+		t.Errorf("random side ignored white %s black %s", game.White.ID, game.Black.ID)
+	}
+	if !game.Rated {
+		t.Error("games between players should be rated")
+	}
+
+	challenge.Computer = DifficultyHard
+	game, _ = challenge.CreateGame(SideWhite)
+	if game.Black.Computer != DifficultyHard || game.White.Computer != DifficultyNone || game.ComputerToMove() {
+		t.Errorf("computer should play black got white %s black %s", game.White.Computer, game.Black.Computer)
+	}
+	if game.Rated {
+		t.Error("games against the computer shouldn't be rated")
+	}
+}
+
+func TestChallengeFromPGN(t *testing.T) {
+	challenge := Challenge{Challenger: "1", Target: "2", Side: SideBlack, PGN: "1. e4 *"}
+
+	game, err := challenge.CreateGame(SideWhite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.Black.ID != "1" || game.Turn != SideBlack || game.Rated {
+		t.Errorf("got black %s turn %s rated %v", game.Black.ID, game.Turn, game.Rated)
+	}
+
+	challenge.PGN = "1. e5 *"
+	if _, err := challenge.CreateGame(SideWhite); err == nil {
+		t.Error("expected an illegal PGN to fail")
+	}
+}
@@ -0,0 +1,155 @@
+package chess
+
+import (
+	"fmt"
+	"time"
+)
+
+//clockSpent the time each side has used less any increments
+type clockSpent struct {
+	white time.Duration
+	black time.Duration
+}
+
+func (c *clockSpent) side(side SideType) *time.Duration {
+	if side == SideWhite {
+		return &c.white
+	}
+
+	return &c.black
+}
+
+//LastMoveTime returns when the player to move started thinking
+func (g *Game) LastMoveTime() time.Time {
+	if at, ok := g.ClockRestarts[len(g.Moves)]; ok {
+		return at
+	}
+
+	if len(g.MoveTimes) > 0 && len(g.MoveTimes) == len(g.Moves) {
+		return g.MoveTimes[len(g.MoveTimes)-1]
+	}
+
+	return g.Created
+}
+
+//updateClock charges the side for the time it took to make a move
+func (g *Game) updateClock(side SideType, elapsed time.Duration) {
+	if !g.TimeControl.Timed() {
+		return
+	}
+
+	*g.spent.side(side) += elapsed - g.TimeControl.Increment
+}
+
+//replayClock works out how much time each side has used from the move times
+func (g *Game) replayClock() {
+	g.spent = clockSpent{}
+	if !g.TimeControl.Timed() || len(g.MoveTimes) != len(g.Moves) {
+		return
+	}
+
+	last := g.Created
+	side := g.initialTurn()
+	for i, at := range g.MoveTimes {
+		if restart, ok := g.ClockRestarts[i]; ok {
+			last = restart
+		}
+		g.updateClock(side, at.Sub(last))
+		last = at
+		side = side.other()
+	}
+}
+
+//Remaining returns how long the side has left on its clock at the time
+func (g *Game) Remaining(side SideType, now time.Time) time.Duration {
+	thinking := time.Duration(0)
+	if side == g.Turn && !g.Over() {
+		thinking = now.Sub(g.LastMoveTime())
+	}
+
+	if g.TimeControl.Correspondence() {
+		return g.TimeControl.PerMove - thinking
+	}
+
+	return g.TimeControl.Base - *g.spent.side(side) - thinking
+}
+
+//Deadline returns when the player to move runs out of time
+func (g *Game) Deadline() time.Time {
+	return g.LastMoveTime().Add(g.Remaining(g.Turn, g.LastMoveTime()))
+}
+
+//CheckFlag ends the game if the player to move has run out of time
+func (g *Game) CheckFlag(now time.Time) bool {
+	if g.Over() || !g.TimeControl.Timed() || g.Remaining(g.Turn, now) > 0 {
+		return false
+	}
+
+	if !g.canMate(g.Turn.other()) {
+		g.Result = DrawBy(ReasonTimeoutVsInsufficientMaterial)
+	} else {
+		g.Result = WinFor(g.Turn.other(), ReasonTimeout)
+	}
+
+	return true
+}
+
+//canMate returns false if the side could never checkmate, even with the
+//other side's pieces helping by blocking its king in
+func (g *Game) canMate(side SideType) bool {
+	knights, bishops := 0, false
+	var bishopColours [2]bool
+	var other [PieceTypeKing + 1]int
+	for r := 0; r < rowHight; r++ {
+		for c := 0; c < rowWidth; c++ {
+			piece := g.board[r][c]
+			if piece.Kind == PieceTypeBishop {
+				bishopColours[(r+c)%2] = true
+			}
+
+			if piece.Side != side {
+				other[piece.Kind]++
+				continue
+			}
+
+			switch piece.Kind {
+			case PieceTypePawn, PieceTypeRook, PieceTypeQueen:
+				return true
+			case PieceTypeKnight:
+				knights++
+			case PieceTypeBishop:
+				bishops = true
+			}
+		}
+	}
+
+	switch {
+	case knights > 1 || (knights == 1 && bishops):
+		return true
+	case knights == 1:
+		//A lone knight mates only when one of the other side's pieces blocks
+		//its king, queens never get in the way without giving check
+		return other[PieceTypePawn]+other[PieceTypeKnight]+other[PieceTypeBishop]+other[PieceTypeRook] > 0
+	}
+
+	//Bishops only ever attack their own colour of cell, so mate needs bishops
+	//on both colours or a pawn or knight to block the king
+	return bishops && ((bishopColours[0] && bishopColours[1]) ||
+		other[PieceTypePawn]+other[PieceTypeKnight] > 0)
+}
+
+//FormatClock formats time left on a clock as h:mm:ss, m:ss or days and hours
+func FormatClock(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd %dh", d/(24*time.Hour), d%(24*time.Hour)/time.Hour)
+	case d >= time.Hour:
+		return fmt.Sprintf("%d:%02d:%02d", d/time.Hour, d%time.Hour/time.Minute, d%time.Minute/time.Second)
+	}
+
+	return fmt.Sprintf("%d:%02d", d/time.Minute, d%time.Minute/time.Second)
+}
@@ -0,0 +1,194 @@
+package chess
+
+import (
+	"testing"
+	"time"
+)
+
+func newTimedGame(t *testing.T, timeControl string) Game {
+	t.Helper()
+
+	tc, err := ParseTimeControl(timeControl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	game := newTestGame()
+	game.TimeControl = tc
+	game.Created = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	return game
+}
+
+func TestClockIncrement(t *testing.T) {
+	game := newTimedGame(t, "5+3")
+	start := game.Created
+
+	game.MakeMoveAt(testMove("e2", "e4"), start.Add(10*time.Second))
+	game.MakeMoveAt(testMove("e7", "e5"), start.Add(70*time.Second))
+
+	now := start.Add(100 * time.Second)
+	if got, want := game.Remaining(SideWhite, now), 5*time.Minute-10*time.Second+3*time.Second-30*time.Second; got != want {
+		t.Errorf("white got %v want %v", got, want)
+	}
+	if got, want := game.Remaining(SideBlack, now), 5*time.Minute-60*time.Second+3*time.Second; got != want {
+		t.Errorf("black got %v want %v", got, want)
+	}
+
+	//Replaying the saved move times gives the same clocks
+	game.ProcessMoves()
+	if got, want := game.Remaining(SideBlack, now), 5*time.Minute-57*time.Second; got != want {
+		t.Errorf("black after replay got %v want %v", got, want)
+	}
+}
+
+func TestCheckFlag(t *testing.T) {
+	game := newTimedGame(t, "1+0")
+	game.MakeMoveAt(testMove("e2", "e4"), game.Created.Add(time.Second))
+
+	if game.CheckFlag(game.Created.Add(time.Minute)) {
+		t.Fatal("black flagged with time left")
+	}
+	if !game.CheckFlag(game.Created.Add(time.Minute + time.Second)) {
+		t.Fatal("black didn't flag when out of time")
+	}
+	if game.Result != WinFor(SideWhite, ReasonTimeout) {
+		t.Errorf("got %+v want white winning on time", game.Result)
+	}
+}
+
+func TestCheckFlagInsufficientMaterial(t *testing.T) {
+	game, err := FromFEN("4k3/8/8/8/8/8/Q7/1n2K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	game.TimeControl = TimeControl{PerMove: 24 * time.Hour}
+
+	if !game.CheckFlag(game.Created.Add(25 * time.Hour)) {
+		t.Fatal("white didn't flag after a day")
+	}
+	if game.Result != DrawBy(ReasonTimeoutVsInsufficientMaterial) {
+		t.Errorf("got %+v want a draw as black can't mate", game.Result)
+	}
+}
+
+func TestCanMate(t *testing.T) {
+	tests := []struct {
+		fen  string
+		want bool
+	}{
+		{"4k3/8/8/8/8/8/8/4K3 w - - 0 1", false},
+		{"4k3/8/8/8/8/8/8/1n2K3 w - - 0 1", false},
+		{"4k3/8/8/8/8/8/Q7/1n2K3 w - - 0 1", false},
+		{"4k3/8/8/8/8/8/4P3/1n2K3 w - - 0 1", true},
+		{"4k3/8/8/8/8/8/R7/1n2K3 w - - 0 1", true},
+		{"4k3/8/8/8/8/8/8/nn2K3 w - - 0 1", true},
+		{"4k3/8/8/8/8/8/R7/2b1K3 w - - 0 1", false},
+		{"4k3/8/8/8/8/8/4N3/2b1K3 w - - 0 1", true},
+		{"4k3/8/8/8/8/8/3B4/2b1K3 w - - 0 1", false},
+		{"4k3/8/8/8/8/8/4B3/2b1K3 w - - 0 1", true},
+		{"4kb2/8/8/8/8/8/8/4K3 w - - 0 1", false},
+		{"4kb2/8/7b/8/8/8/8/4K3 w - - 0 1", false},
+		{"4kbb1/8/8/8/8/8/8/4K3 w - - 0 1", true},
+	}
+
+	for _, test := range tests {
+		game, err := FromFEN(test.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := game.canMate(SideBlack); got != test.want {
+			t.Errorf("%s: got %v want %v", test.fen, got, test.want)
+		}
+	}
+}
+
+func TestCheckFlagHelpmate(t *testing.T) {
+	//Black's knight can mate if white's pawn blocks its king in
+	game, err := FromFEN("4k3/8/8/8/8/8/4P3/1n2K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	game.TimeControl = TimeControl{PerMove: 24 * time.Hour}
+
+	if !game.CheckFlag(game.Created.Add(25 * time.Hour)) {
+		t.Fatal("white didn't flag after a day")
+	}
+	if game.Result != WinFor(SideBlack, ReasonTimeout) {
+		t.Errorf("got %+v want black winning on time", game.Result)
+	}
+}
+
+func TestFormatClock(t *testing.T) {
+	tests := map[time.Duration]string{
+		-time.Second:                         "0:00",
+		65 * time.Second:                     "1:05",
+		time.Hour + 2*time.Minute:            "1:02:00",
+		50 * time.Hour:                       "2d 2h",
+		3*time.Minute + 500*time.Millisecond: "3:00",
+	}
+
+	for d, want := range tests {
+		if got := FormatClock(d); got != want {
+			t.Errorf("%v: got %s want %s", d, got, want)
+		}
+	}
+}
+
+func TestMoveResetsReminder(t *testing.T) {
+	game := newTimedGame(t, "3d")
+	game.Reminded = true
+
+	at := game.Created.Add(time.Hour)
+	game.MakeMoveAt(testMove("e2", "e4"), at)
+	if game.Reminded {
+		t.Error("reminder still marked as sent after a move")
+	}
+	if !game.LastMoveTime().Equal(at) {
+		t.Errorf("last move time got %v want %v", game.LastMoveTime(), at)
+	}
+}
+
+func TestClockAfterTakeback(t *testing.T) {
+	game := newTimedGame(t, "5+0")
+	start := game.Created
+
+	game.MakeMoveAt(testMove("e2", "e4"), start.Add(10*time.Second))
+	game.RequestTakeback(game.White.ID)
+	if err := game.AcceptTakebackAt(game.Black.ID, start.Add(100*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	//White isn't charged for the time black spent thinking
+	at := start.Add(100 * time.Second)
+	if got := game.Remaining(SideWhite, at); got != 5*time.Minute {
+		t.Errorf("white got %v want 5m0s", got)
+	}
+
+	game.MakeMoveAt(testMove("d2", "d4"), at.Add(30*time.Second))
+	game.ProcessMoves()
+	if got, want := game.Remaining(SideWhite, at), 5*time.Minute-30*time.Second; got != want {
+		t.Errorf("white after replay got %v want %v", got, want)
+	}
+}
+
+func TestCorrespondenceDeadlineAfterTakeback(t *testing.T) {
+	game := newTimedGame(t, "3d")
+	start := game.Created
+
+	game.MakeMoveAt(testMove("e2", "e4"), start.Add(time.Hour))
+	game.RequestTakeback(game.White.ID)
+
+	//The takeback is accepted after white's old deadline has passed
+	at := start.Add(84 * time.Hour)
+	if err := game.AcceptTakebackAt(game.Black.ID, at); err != nil {
+		t.Fatal(err)
+	}
+
+	if game.CheckFlag(at.Add(time.Minute)) {
+		t.Fatal("white forfeited straight after a takeback")
+	}
+	if got, want := game.Deadline(), at.Add(72*time.Hour); !got.Equal(want) {
+		t.Errorf("got deadline %v want %v", got, want)
+	}
+}
@@ -0,0 +1,347 @@
+package chess
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+//Difficulty how strong the computer plays
+type Difficulty int
+
+const (
+	//DifficultyNone the player is a person
+	DifficultyNone Difficulty = iota
+	//DifficultyEasy a shallow search that often picks a worse move
+	DifficultyEasy
+	//DifficultyMedium a short search with a little randomness
+	DifficultyMedium
+	//DifficultyHard the deepest search the time budget allows
+	DifficultyHard
+)
+
+//difficultyLimits how each difficulty searches
+var difficultyLimits = map[Difficulty]SearchLimits{
+	DifficultyEasy:   {Depth: 1, Time: 200 * time.Millisecond, Noise: 150},
+	DifficultyMedium: {Depth: 3, Time: time.Second, Noise: 25},
+	DifficultyHard:   {Depth: maxSearchDepth, Time: 3 * time.Second},
+}
+
+//ParseDifficulty reads easy, medium or hard
+func ParseDifficulty(str string) (Difficulty, error) {
+	switch strings.ToLower(str) {
+	case "easy":
+		return DifficultyEasy, nil
+	case "", "medium":
+		return DifficultyMedium, nil
+	case "hard":
+		return DifficultyHard, nil
+	}
+
+	return DifficultyNone, errors.Errorf("unknown difficulty %s try easy, medium or hard", str)
+}
+
+func (d Difficulty) String() string {
+	switch d {
+	case DifficultyEasy:
+		return "easy"
+	case DifficultyMedium:
+		return "medium"
+	case DifficultyHard:
+		return "hard"
+	}
+
+	return "none"
+}
+
+const (
+	maxSearchDepth = 32
+	//mateScore the score for giving checkmate less the plies it takes
+	mateScore = 100000
+	//mateThreshold scores past this are forced mates
+	mateThreshold = mateScore - 1000
+	infinity      = mateScore + 1
+	//nodesPerClockCheck how many positions are searched between checking the time
+	nodesPerClockCheck = 1024
+)
+
+//SearchLimits how long and how deep to search
+type SearchLimits struct {
+	Depth int
+	Time  time.Duration
+	//Noise picks a random move scoring within this many centipawns of the best
+	Noise int
+}
+
+//SearchResult the best move found and its score for the side to move
+type SearchResult struct {
+	Move Move
+	//Score centipawns for the side to move
+	Score int
+	//Depth the deepest search that finished
+	Depth int
+}
+
+//MateIn returns the moves until mate, negative when being mated and 0 if there isn't one
+func (r SearchResult) MateIn() int {
+	switch {
+	case r.Score > mateThreshold:
+		return (mateScore - r.Score + 1) / 2
+	case r.Score < -mateThreshold:
+		return -(mateScore + r.Score + 1) / 2
+	}
+
+	return 0
+}
+
+//searcher holds the state of a single search
+type searcher struct {
+	game     Game
+	deadline time.Time
+	nodes    int
+	stopped  bool
+}
+
+//makeMove plays a move on the searcher's board returning false if it was illegal
+func (s *searcher) makeMove(mv Move) bool {
+	side := s.game.Turn
+	s.game.processMove(mv)
+	s.game.Turn = side.other()
+
+	return !s.game.position.inCheck(side)
+}
+
+//moveOrder scores captures of valuable pieces by cheap pieces first
+func (s *searcher) moveOrder(mv Move) int {
+	victim := s.game.board[mv.To.Row][mv.To.Col]
+	attacker := s.game.board[mv.From.Row][mv.From.Col]
+
+	score := 0
+	if victim.Kind != PieceTypeEmpty {
+		score += 10*pieceValues[victim.Kind] - pieceValues[attacker.Kind]
+	}
+
+	return score + pieceValues[mv.Promotion]
+}
+
+func (s *searcher) orderMoves(moves []Move, first Move) {
+	sort.SliceStable(moves, func(i, j int) bool {
+		if moves[i] == first || moves[j] == first {
+			return moves[i] == first
+		}
+		return s.moveOrder(moves[i]) > s.moveOrder(moves[j])
+	})
+}
+
+//timeUp checks the clock every so often
+func (s *searcher) timeUp() bool {
+	s.nodes++
+	if s.nodes%nodesPerClockCheck == 0 && time.Now().After(s.deadline) {
+		s.stopped = true
+	}
+
+	return s.stopped
+}
+
+//quiesce keeps searching captures so the score isn't taken mid exchange
+func (s *searcher) quiesce(alpha, beta int) int {
+	if s.timeUp() {
+		return 0
+	}
+
+	standPat := s.game.position.evaluate(s.game.Turn)
+	if standPat >= beta {
+		return beta
+	}
+	if standPat > alpha {
+		alpha = standPat
+	}
+
+	moves := s.game.position.pseudoMoves(s.game.Turn, true)
+	s.orderMoves(moves, Move{})
+
+	for _, mv := range moves {
+		prev, turn := s.game.position, s.game.Turn
+		legal := s.makeMove(mv)
+		score := alpha
+		if legal {
+			score = -s.quiesce(-beta, -alpha)
+		}
+		s.game.position, s.game.Turn = prev, turn
+
+		if s.stopped {
+			return 0
+		}
+		if score >= beta {
+			return beta
+		}
+		if score > alpha {
+			alpha = score
+		}
+	}
+
+	return alpha
+}
+
+//negamax an alpha beta search returning the score for the side to move
+func (s *searcher) negamax(depth, ply, alpha, beta int) int {
+	if s.timeUp() {
+		return 0
+	}
+
+	if s.game.halfMove >= 100 || s.game.insufficientMaterial() {
+		return 0
+	}
+
+	if depth <= 0 {
+		return s.quiesce(alpha, beta)
+	}
+
+	moves := s.game.position.pseudoMoves(s.game.Turn, false)
+	s.orderMoves(moves, Move{})
+
+	legalMoves := 0
+	for _, mv := range moves {
+		prev, turn := s.game.position, s.game.Turn
+		legal := s.makeMove(mv)
+		score := alpha
+		if legal {
+			legalMoves++
+			score = -s.negamax(depth-1, ply+1, -beta, -alpha)
+		}
+		s.game.position, s.game.Turn = prev, turn
+
+		if s.stopped {
+			return 0
+		}
+		if score >= beta {
+			return beta
+		}
+		if score > alpha {
+			alpha = score
+		}
+	}
+
+	if legalMoves == 0 {
+		if s.game.position.inCheck(s.game.Turn) {
+			return -mateScore + ply
+		}
+		return 0
+	}
+
+	return alpha
+}
+
+//rootMove a move from the position being searched and its score
+type rootMove struct {
+	mv    Move
+	score int
+}
+
+//searchRoot scores every move from the position, noisy searches need
+//an exact score for each move so they can pick between them
+func (s *searcher) searchRoot(moves []Move, depth int, exact bool) []rootMove {
+	result := make([]rootMove, 0, len(moves))
+	alpha := -infinity
+
+	for _, mv := range moves {
+		prev, turn := s.game.position, s.game.Turn
+		s.makeMove(mv)
+		window := alpha
+		if exact {
+			window = -infinity
+		}
+		score := -s.negamax(depth-1, 1, -infinity, -window)
+		s.game.position, s.game.Turn = prev, turn
+
+		if s.stopped {
+			return nil
+		}
+
+		result = append(result, rootMove{mv, score})
+		if score > alpha {
+			alpha = score
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].score > result[j].score
+	})
+
+	return result
+}
+
+//Search looks for the best move for the side to move within the limits
+func (g *Game) Search(limits SearchLimits) (SearchResult, error) {
+	if g.Over() {
+		return SearchResult{}, ErrGameOver
+	}
+
+	s := searcher{game: *g, deadline: time.Now().Add(limits.Time)}
+	s.game.repetitions = nil
+
+	moves := g.LegalMoves()
+	if len(moves) == 0 {
+		return SearchResult{}, errors.New("there are no legal moves")
+	}
+
+	depthLimit := limits.Depth
+	if depthLimit <= 0 || depthLimit > maxSearchDepth {
+		depthLimit = maxSearchDepth
+	}
+
+	var scored []rootMove
+	depth := 0
+	s.orderMoves(moves, Move{})
+	for d := 1; d <= depthLimit; d++ {
+		result := s.searchRoot(moves, d, limits.Noise > 0)
+		if result == nil {
+			break
+		}
+
+		scored, depth = result, d
+		//Search the best move first next time
+		for i := range moves {
+			moves[i] = result[i].mv
+		}
+
+		if result[0].score > mateThreshold || result[0].score < -mateThreshold {
+			break
+		}
+	}
+
+	//Always have something to play even if the first search ran out of time
+	if scored == nil {
+		return SearchResult{Move: moves[0], Score: s.game.position.evaluate(g.Turn)}, nil
+	}
+
+	best := scored[0]
+	if limits.Noise > 0 {
+		choices := 1
+		for choices < len(scored) && scored[choices].score >= best.score-limits.Noise {
+			choices++
+		}
+		best = scored[rand.Intn(choices)]
+	}
+
+	return SearchResult{Move: best.mv, Score: best.score, Depth: depth}, nil
+}
+
+//ComputerToMove returns true if the engine should make the next move
+func (g *Game) ComputerToMove() bool {
+	return !g.Over() && g.GetSidePlayer(g.Turn).Computer != DifficultyNone
+}
+
+//BestMove picks the computer's move at the difficulty
+func (g *Game) BestMove(difficulty Difficulty) (Move, error) {
+	limits, ok := difficultyLimits[difficulty]
+	if !ok {
+		return Move{}, errors.Errorf("unknown difficulty %d", difficulty)
+	}
+
+	result, err := g.Search(limits)
+	return result.Move, err
+}
@@ -0,0 +1,51 @@
+package chess
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSearchFindsTactics(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		want string
+		mate int
+	}{
+		{"back rank mate", "6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1", "a1a8", 1},
+		{"hanging queen", "4k3/8/8/3q4/8/8/3R4/4K3 w - - 0 1", "d2d5", 0},
+		{"mate in two", "r2qkb1r/pp2nppp/3p4/2pNN1B1/2BnP3/3P4/PPP2PPP/R2bK2R w KQkq - 1 1", "d5f6", 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			game, err := FromFEN(test.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := game.Search(SearchLimits{Depth: 4, Time: 10 * time.Second})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Move.UCI() != test.want || result.MateIn() != test.mate {
+				t.Errorf("got %s mate in %d want %s mate in %d", result.Move.UCI(), result.MateIn(), test.want, test.mate)
+			}
+		})
+	}
+}
+
+func TestBestMoveIsLegal(t *testing.T) {
+	game := newTestGame()
+
+	for _, difficulty := range []Difficulty{DifficultyEasy, DifficultyMedium} {
+		mv, err := game.BestMove(difficulty)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := game.ValidMove(game.GetSidePlayer(game.Turn).ID, mv); err != nil {
+			t.Errorf("%s picked %s: %v", difficulty, mv.UCI(), err)
+		}
+		game.MakeMove(mv)
+	}
+}
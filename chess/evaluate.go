@@ -0,0 +1,132 @@
+package chess
+
+//pieceValues the material value of each piece in centipawns
+var pieceValues = [...]int{
+	PieceTypeEmpty:  0,
+	PieceTypePawn:   100,
+	PieceTypeKnight: 320,
+	PieceTypeBishop: 330,
+	PieceTypeRook:   500,
+	PieceTypeQueen:  900,
+	PieceTypeKing:   0,
+}
+
+//pieceSquares bonuses for where pieces stand written from white's side of
+//the board with row 0 being the eighth rank
+var pieceSquares = [...][rowHight][rowWidth]int{
+	PieceTypePawn: {
+		{0, 0, 0, 0, 0, 0, 0, 0},
+		{50, 50, 50, 50, 50, 50, 50, 50},
+		{10, 10, 20, 30, 30, 20, 10, 10},
+		{5, 5, 10, 25, 25, 10, 5, 5},
+		{0, 0, 0, 20, 20, 0, 0, 0},
+		{5, -5, -10, 0, 0, -10, -5, 5},
+		{5, 10, 10, -20, -20, 10, 10, 5},
+		{0, 0, 0, 0, 0, 0, 0, 0},
+	},
+	PieceTypeKnight: {
+		{-50, -40, -30, -30, -30, -30, -40, -50},
+		{-40, -20, 0, 0, 0, 0, -20, -40},
+		{-30, 0, 10, 15, 15, 10, 0, -30},
+		{-30, 5, 15, 20, 20, 15, 5, -30},
+		{-30, 0, 15, 20, 20, 15, 0, -30},
+		{-30, 5, 10, 15, 15, 10, 5, -30},
+		{-40, -20, 0, 5, 5, 0, -20, -40},
+		{-50, -40, -30, -30, -30, -30, -40, -50},
+	},
+	PieceTypeBishop: {
+		{-20, -10, -10, -10, -10, -10, -10, -20},
+		{-10, 0, 0, 0, 0, 0, 0, -10},
+		{-10, 0, 5, 10, 10, 5, 0, -10},
+		{-10, 5, 5, 10, 10, 5, 5, -10},
+		{-10, 0, 10, 10, 10, 10, 0, -10},
+		{-10, 10, 10, 10, 10, 10, 10, -10},
+		{-10, 5, 0, 0, 0, 0, 5, -10},
+		{-20, -10, -10, -10, -10, -10, -10, -20},
+	},
+	PieceTypeRook: {
+		{0, 0, 0, 0, 0, 0, 0, 0},
+		{5, 10, 10, 10, 10, 10, 10, 5},
+		{-5, 0, 0, 0, 0, 0, 0, -5},
+		{-5, 0, 0, 0, 0, 0, 0, -5},
+		{-5, 0, 0, 0, 0, 0, 0, -5},
+		{-5, 0, 0, 0, 0, 0, 0, -5},
+		{-5, 0, 0, 0, 0, 0, 0, -5},
+		{0, 0, 0, 5, 5, 0, 0, 0},
+	},
+	PieceTypeQueen: {
+		{-20, -10, -10, -5, -5, -10, -10, -20},
+		{-10, 0, 0, 0, 0, 0, 0, -10},
+		{-10, 0, 5, 5, 5, 5, 0, -10},
+		{-5, 0, 5, 5, 5, 5, 0, -5},
+		{0, 0, 5, 5, 5, 5, 0, -5},
+		{-10, 5, 5, 5, 5, 5, 0, -10},
+		{-10, 0, 5, 0, 0, 0, 0, -10},
+		{-20, -10, -10, -5, -5, -10, -10, -20},
+	},
+	PieceTypeKing: {
+		{-30, -40, -40, -50, -50, -40, -40, -30},
+		{-30, -40, -40, -50, -50, -40, -40, -30},
+		{-30, -40, -40, -50, -50, -40, -40, -30},
+		{-30, -40, -40, -50, -50, -40, -40, -30},
+		{-20, -30, -30, -40, -40, -30, -30, -20},
+		{-10, -20, -20, -20, -20, -20, -20, -10},
+		{20, 20, 0, 0, 0, 0, 20, 20},
+		{20, 30, 10, 0, 0, 10, 30, 20},
+	},
+}
+
+//kingEndgameSquares the king should come to the middle once the queens are off
+var kingEndgameSquares = [rowHight][rowWidth]int{
+	{-50, -40, -30, -20, -20, -30, -40, -50},
+	{-30, -20, -10, 0, 0, -10, -20, -30},
+	{-30, -10, 20, 30, 30, 20, -10, -30},
+	{-30, -10, 30, 40, 40, 30, -10, -30},
+	{-30, -10, 30, 40, 40, 30, -10, -30},
+	{-30, -10, 20, 30, 30, 20, -10, -30},
+	{-30, -30, 0, 0, 0, 0, -30, -30},
+	{-50, -30, -30, -30, -30, -30, -30, -50},
+}
+
+//evaluate scores the position in centipawns for the side
+func (p *position) evaluate(side SideType) int {
+	score := 0
+	queens := 0
+	for r := 0; r < rowHight; r++ {
+		for c := 0; c < rowWidth; c++ {
+			if p.board[r][c].Kind == PieceTypeQueen {
+				queens++
+			}
+		}
+	}
+
+	for r := 0; r < rowHight; r++ {
+		for c := 0; c < rowWidth; c++ {
+			piece := p.board[r][c]
+			if piece.Kind == PieceTypeEmpty {
+				continue
+			}
+
+			//Black reads the tables from its side of the board
+			row := r
+			if piece.Side == SideBlack {
+				row = rowHight - 1 - r
+			}
+
+			value := pieceValues[piece.Kind]
+			if piece.Kind == PieceTypeKing && queens == 0 {
+				value += kingEndgameSquares[row][c]
+			} else {
+				value += pieceSquares[piece.Kind][row][c]
+			}
+
+			if piece.Side == side {
+				score += value
+			} else {
+				score -= value
+			}
+		}
+	}
+
+	return score
+}
@@ -0,0 +1,267 @@
+package chess
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+//StartingFEN the FEN of a normal starting position
+const StartingFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+//castlingFEN the FEN letter for each castling right in the order they are written
+var castlingFEN = []struct {
+	right  castlingRights
+	letter byte
+}{
+	{castleWhiteKing, 'K'}, {castleWhiteQueen, 'Q'},
+	{castleBlackKing, 'k'}, {castleBlackQueen, 'q'},
+}
+
+//fenLetters the FEN letter for each piece lower case is black
+var fenLetters = map[PieceType]string{
+	PieceTypePawn:   "p",
+	PieceTypeKnight: "n",
+	PieceTypeBishop: "b",
+	PieceTypeRook:   "r",
+	PieceTypeQueen:  "q",
+	PieceTypeKing:   "k",
+}
+
+//fenLetter returns the FEN letter for a piece upper case for white
+func fenLetter(p Piece) string {
+	if p.Side == SideWhite {
+		return strings.ToUpper(fenLetters[p.Kind])
+	}
+	return fenLetters[p.Kind]
+}
+
+//FEN returns the current position in Forsyth–Edwards Notation
+func (g *Game) FEN() string {
+	return fmt.Sprintf(
+		"%s %d %d",
+		g.fenPosition(g.Turn, g.canEnPassant), g.halfMove, g.fullMove,
+	)
+}
+
+//fenPosition returns the FEN fields describing the position without the
+//move counters
+func (g *Game) fenPosition(turn SideType, withEnPassant bool) string {
+	var result strings.Builder
+
+	for r := 0; r < rowHight; r++ {
+		empty := 0
+		for c := 0; c < rowWidth; c++ {
+			piece := g.board[r][c]
+			if piece.Kind == PieceTypeEmpty {
+				empty++
+				continue
+			}
+
+			if empty > 0 {
+				fmt.Fprintf(&result, "%d", empty)
+				empty = 0
+			}
+			result.WriteString(fenLetter(piece))
+		}
+
+		if empty > 0 {
+			fmt.Fprintf(&result, "%d", empty)
+		}
+		if r != rowHight-1 {
+			result.WriteByte('/')
+		}
+	}
+
+	turnStr := "w"
+	if turn == SideBlack {
+		turnStr = "b"
+	}
+
+	castling := ""
+	for _, val := range castlingFEN {
+		if g.castling&val.right != 0 {
+			castling += string(val.letter)
+		}
+	}
+	if castling == "" {
+		castling = "-"
+	}
+
+	enPassant := "-"
+	if withEnPassant {
+		enPassant = strings.ToLower(g.enPassant.String())
+	}
+
+	fmt.Fprintf(&result, " %s %s %s", turnStr, castling, enPassant)
+
+	return result.String()
+}
+
+//parseFEN reads a FEN string into a position and the side to move
+func parseFEN(fen string) (position, SideType, error) {
+	var result position
+
+	fields := strings.Fields(fen)
+	if len(fields) != 6 {
+		return result, SideEmpty, errors.Errorf("FEN needs 6 fields found %d", len(fields))
+	}
+
+	ranks := strings.Split(fields[0], "/")
+	if len(ranks) != rowHight {
+		return result, SideEmpty, errors.Errorf("FEN needs %d ranks found %d", rowHight, len(ranks))
+	}
+
+	kings := map[SideType]int{}
+	for r, rank := range ranks {
+		c := 0
+		for _, char := range rank {
+			if char >= '1' && char <= '8' {
+				c += int(char - '0')
+				continue
+			}
+
+			kind, err := ParsePieceType(string(char))
+			if err != nil {
+				return result, SideEmpty, errors.Errorf("unknown piece %c in FEN", char)
+			}
+			if c >= rowWidth {
+				return result, SideEmpty, errors.Errorf("FEN rank %d has too many cells", rowHight-r)
+			}
+
+			side := SideBlack
+			if unicode.IsUpper(char) {
+				side = SideWhite
+			}
+			if kind == PieceTypeKing {
+				kings[side]++
+			}
+			if kind == PieceTypePawn && (r == 0 || r == rowHight-1) {
+				return result, SideEmpty, errors.New("pawns cannot be on the first or last rank")
+			}
+
+			result.board[r][c] = Piece{kind, side}
+			c++
+		}
+
+		if c != rowWidth {
+			return result, SideEmpty, errors.Errorf("FEN rank %d does not have %d cells", rowHight-r, rowWidth)
+		}
+	}
+
+	if kings[SideWhite] != 1 || kings[SideBlack] != 1 {
+		return result, SideEmpty, errors.New("FEN must have one king for each side")
+	}
+
+	var turn SideType
+	switch fields[1] {
+	case "w":
+		turn = SideWhite
+	case "b":
+		turn = SideBlack
+	default:
+		return result, SideEmpty, errors.Errorf("unknown side to move %s", fields[1])
+	}
+
+	if fields[2] != "-" {
+		for _, char := range fields[2] {
+			found := false
+			for _, val := range castlingFEN {
+				if byte(char) == val.letter {
+					result.castling |= val.right
+					found = true
+				}
+			}
+			if !found {
+				return result, SideEmpty, errors.Errorf("unknown castling right %c", char)
+			}
+		}
+	}
+
+	//Castling needs the king and rook to still be where they started
+	for _, side := range []SideType{SideWhite, SideBlack} {
+		for _, kingSide := range []bool{true, false} {
+			if result.castling&castlingRight(side, kingSide) == 0 {
+				continue
+			}
+
+			mv := CastlingMove(side, kingSide)
+			rook := castlingRookMove(mv).From
+			if result.board[mv.From.Row][mv.From.Col] != (Piece{PieceTypeKing, side}) ||
+				result.board[rook.Row][rook.Col] != (Piece{PieceTypeRook, side}) {
+				return result, SideEmpty, errors.Errorf(
+					"%s cannot castle without the king and rook on their starting cells", side.String(),
+				)
+			}
+		}
+	}
+
+	if fields[3] != "-" {
+		//The cell is behind a pawn of the side that just moved two cells
+		moved := turn.other()
+		wantRank := byte('3')
+		if moved == SideBlack {
+			wantRank = '6'
+		}
+		if len(fields[3]) != 2 || fields[3][0] < 'a' || fields[3][0] > 'h' || fields[3][1] != wantRank {
+			return result, SideEmpty, errors.Errorf("invalid en passant cell %s", fields[3])
+		}
+
+		enPassant := StringToPostion(fields[3])
+		pawn := Postion{enPassant.Row + forward(moved), enPassant.Col}
+		from := Postion{enPassant.Row - forward(moved), enPassant.Col}
+		if result.board[pawn.Row][pawn.Col] != (Piece{PieceTypePawn, moved}) ||
+			result.board[enPassant.Row][enPassant.Col].Kind != PieceTypeEmpty ||
+			result.board[from.Row][from.Col].Kind != PieceTypeEmpty {
+			return result, SideEmpty, errors.Errorf("no pawn could have just moved past %s", fields[3])
+		}
+
+		result.enPassant = enPassant
+		result.canEnPassant = true
+	}
+
+	if result.inCheck(turn.other()) {
+		return result, SideEmpty, errors.Errorf("%s is in check but it isn't their move", turn.other().String())
+	}
+
+	halfMove, err := strconv.Atoi(fields[4])
+	if err != nil || halfMove < 0 {
+		return result, SideEmpty, errors.Errorf("invalid halfmove clock %s", fields[4])
+	}
+	result.halfMove = halfMove
+
+	fullMove, err := strconv.Atoi(fields[5])
+	if err != nil || fullMove < 1 {
+		return result, SideEmpty, errors.Errorf("invalid fullmove number %s", fields[5])
+	}
+	result.fullMove = fullMove
+
+	return result, turn, nil
+}
+
+//FromFEN creates a game starting from the position in the FEN string
+func FromFEN(fen string) (Game, error) {
+	_, turn, err := parseFEN(fen)
+	if err != nil {
+		return Game{}, errors.Wrap(err, "invalid FEN")
+	}
+
+	result := Game{
+		UID:             NewGameID(),
+		White:           Player{Side: SideWhite, Color: color.RGBA{255, 255, 255, 255}},
+		Black:           Player{Side: SideBlack, Color: color.RGBA{0, 0, 0, 255}},
+		Turn:            turn,
+		StartFEN:        fen,
+		BoardColorBlack: color.RGBA{0, 0, 0, 255},
+		BoardColorWhite: color.RGBA{255, 255, 255, 255},
+	}
+	result.ProcessMoves()
+	//The position might already be over
+	result.updateResult()
+
+	return result, nil
+}
@@ -0,0 +1,110 @@
+package chess
+
+import (
+	"testing"
+)
+
+func TestFENRoundTrip(t *testing.T) {
+	fens := []string{
+		StartingFEN,
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"r3k2r/8/8/8/8/8/8/R3K2R b Kq - 12 40",
+		"rnbqkbnr/ppp1pppp/8/3pP3/8/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 3",
+		"rnbqkbnr/pppp1ppp/8/8/3pP3/8/PPP2PPP/RNBQKBNR b KQkq e3 0 3",
+		"8/8/8/8/8/8/8/k6K w - - 99 120",
+	}
+
+	for _, fen := range fens {
+		game, err := FromFEN(fen)
+		if err != nil {
+			t.Errorf("%s rejected: %v", fen, err)
+			continue
+		}
+
+		if got := game.FEN(); got != fen {
+			t.Errorf("round trip got %s want %s", got, fen)
+		}
+	}
+}
+
+func TestFENAfterMoves(t *testing.T) {
+	game := newTestGame()
+
+	playSAN(t, &game, "e4")
+	if got, want := game.FEN(), "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"; got != want {
+		t.Errorf("after e4 got %s want %s", got, want)
+	}
+
+	playSAN(t, &game, "Nf6 Ke2")
+	if got, want := game.FEN(), "rnbqkb1r/pppppppp/5n2/8/4P3/8/PPPPKPPP/RNBQ1BNR b kq - 2 2"; got != want {
+		t.Errorf("after Ke2 got %s want %s", got, want)
+	}
+}
+
+func TestFENResult(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		want Result
+	}{
+		{"ongoing", StartingFEN, Result{}},
+		{"mated", "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3", WinFor(SideBlack, ReasonCheckmate)},
+		{"stalemated", "7k/5Q2/6K1/8/8/8/8/8 b - - 0 1", DrawBy(ReasonStalemate)},
+	}
+
+	for _, test := range tests {
+		game, err := FromFEN(test.fen)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		if game.Result != test.want {
+			t.Errorf("%s: got %+v want %+v", test.name, game.Result, test.want)
+		}
+	}
+}
+
+func TestFENRejections(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+	}{
+		{"missing fields", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -"},
+		{"missing rank", "rnbqkbnr/pppppppp/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"},
+		{"long rank", "rnbqkbnr/pppppppp/9/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"},
+		{"unknown piece", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNX w KQkq - 0 1"},
+		{"two kings", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBKKBNR w kq - 0 1"},
+		{"no king", "rnbq1bnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQ - 0 1"},
+		{"pawn on the last rank", "rnbqkbnP/pppppppp/8/8/8/8/PPPPPPP1/RNBQKBNR w KQq - 0 1"},
+		{"unknown side", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR x KQkq - 0 1"},
+		{"unknown castling", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkx - 0 1"},
+		{"castling without the rook", "rnbqkbn1/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"},
+		{"castling after the king moved", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQ1BNR w KQkq - 0 1"},
+		{"castling with a moved king", "r3k2r/8/8/8/8/8/8/R4K1R w K - 0 1"},
+		{"en passant on the wrong rank", "rnbqkbnr/pppp1ppp/8/4p3/8/8/PPPPPPPP/RNBQKBNR w KQkq e3 0 2"},
+		{"en passant for the side to move", "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e3 0 1"},
+		{"en passant without a pawn", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq e6 0 1"},
+		{"en passant with the start cell filled", "rnbqkbnr/pppp1ppp/4p3/4p3/8/8/PPPPPPPP/RNBQKBNR w KQkq e6 0 2"},
+		{"side not to move in check", "4k2R/8/8/8/8/8/8/4K3 w - - 0 1"},
+		{"king can be taken", "4k3/4R3/8/8/8/8/8/4K3 w - - 0 1"},
+		{"bad halfmove clock", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - x 1"},
+		{"bad fullmove number", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 0"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := FromFEN(test.fen); err == nil {
+				t.Errorf("%s accepted", test.fen)
+			}
+		})
+	}
+}
+
+func TestSideInCheckWithoutKing(t *testing.T) {
+	game := newTestGame()
+	game.board[7][4] = Piece{}
+
+	if err := game.sideInCheck(SideWhite); err == nil {
+		t.Error("a side without a king should be an error")
+	}
+}
@@ -2,6 +2,8 @@ package chess
 
 import (
 	"bytes"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"image"
 	"image/color"
@@ -14,6 +16,8 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -21,17 +25,20 @@ import (
 const (
 	rowWidth = 8
 	rowHight = 8
+	//gameIDLength the number of hex characters in a game id
+	gameIDLength = 8
 )
 
 type validMove func(g *Game, mv Move) error
 
 var (
-	images     = make(map[PieceType]image.Image)
-	moves      = make(map[PieceType]validMove)
-	boardImg   image.Image
-	emptyBoard [rowHight][rowWidth]Piece
-	green      = color.RGBA{0, 255, 0, 255}
-	purple     = color.RGBA{255, 0, 255, 255}
+	images        = make(map[PieceType]image.Image)
+	moves         = make(map[PieceType]validMove)
+	boardImg      image.Image
+	assetsOnce    sync.Once
+	startPosition position
+	green         = color.RGBA{0, 255, 0, 255}
+	purple        = color.RGBA{255, 0, 255, 255}
 )
 
 func init() {
@@ -44,16 +51,6 @@ func init() {
 		PieceTypeKing:   validKingMove,
 	}
 
-	// Loads assets
-	boardImg = loadImage("assets/chess_board.png")
-
-	images[PieceTypePawn] = loadPieceImg("pawn")
-	images[PieceTypeKnight] = loadPieceImg("knight")
-	images[PieceTypeBishop] = loadPieceImg("bishop")
-	images[PieceTypeRook] = loadPieceImg("rook")
-	images[PieceTypeQueen] = loadPieceImg("queen")
-	images[PieceTypeKing] = loadPieceImg("king")
-
 	var board [rowHight][rowWidth]Piece
 
 	coolPieceRow := [rowWidth]Piece{
@@ -75,7 +72,25 @@ func init() {
 	board[rowHight-2] = changeRowSide(pawnRow, SideWhite)
 	board[rowHight-1] = changeRowSide(coolPieceRow, SideWhite)
 
-	emptyBoard = board
+	startPosition = position{
+		board:    board,
+		castling: castleWhiteKing | castleWhiteQueen | castleBlackKing | castleBlackQueen,
+		fullMove: 1,
+	}
+}
+
+//loadAssets loads the images the first time a board is drawn
+func loadAssets() {
+	assetsOnce.Do(func() {
+		boardImg = loadImage("assets/chess_board.png")
+
+		images[PieceTypePawn] = loadPieceImg("pawn")
+		images[PieceTypeKnight] = loadPieceImg("knight")
+		images[PieceTypeBishop] = loadPieceImg("bishop")
+		images[PieceTypeRook] = loadPieceImg("rook")
+		images[PieceTypeQueen] = loadPieceImg("queen")
+		images[PieceTypeKing] = loadPieceImg("king")
+	})
 }
 
 func loadImage(path string) image.Image {
@@ -153,13 +168,13 @@ func (p PieceType) String() string {
 	}
 }
 
-//NotationStr returns algerbaric notation symbol for piece
+//NotationStr returns algerbaric notation symbol for piece pawns have none
 func (p PieceType) NotationStr() string {
 	switch p {
 	case PieceTypePawn:
-		return "P"
+		return ""
 	case PieceTypeKnight:
-		return "K"
+		return "N"
 	case PieceTypeBishop:
 		return "B"
 	case PieceTypeRook:
@@ -173,6 +188,26 @@ func (p PieceType) NotationStr() string {
 	}
 }
 
+//ParsePieceType returns the piece type from a name or notation symbol
+func ParsePieceType(str string) (PieceType, error) {
+	switch strings.ToLower(str) {
+	case "p", "pawn":
+		return PieceTypePawn, nil
+	case "n", "knight":
+		return PieceTypeKnight, nil
+	case "b", "bishop":
+		return PieceTypeBishop, nil
+	case "r", "rook":
+		return PieceTypeRook, nil
+	case "q", "queen":
+		return PieceTypeQueen, nil
+	case "k", "king":
+		return PieceTypeKing, nil
+	}
+
+	return PieceTypeEmpty, errors.Errorf("unknown piece %s", str)
+}
+
 //SideType SideType
 type SideType int
 
@@ -222,6 +257,8 @@ type Player struct {
 	ID    string     `json:"id"`
 	Side  SideType   `json:"side"`
 	Color color.RGBA `json:"color"`
+	//Computer the engine difficulty when the computer plays this side
+	Computer Difficulty `json:"computer,omitempty"`
 }
 
 //Postion Postion
@@ -233,12 +270,12 @@ type Postion struct {
 func (p *Postion) String() string {
 	return fmt.Sprintf(
 		"%s%d",
-		string(p.Col+int('A')), 8-p.Row,
+		string(rune(p.Col+int('A'))), 8-p.Row,
 	)
 }
 
 func colStr(c int) string {
-	return fmt.Sprintf("%s", c+int('A'))
+	return fmt.Sprintf("%c", c+int('a'))
 }
 
 func rankStr(r int) string {
@@ -252,33 +289,121 @@ type Move struct {
 	Promotion PieceType `json:"promo"`
 }
 
+//position state of the board derived from replaying the moves
+type position struct {
+	board        [rowHight][rowWidth]Piece
+	castling     castlingRights
+	enPassant    Postion
+	canEnPassant bool
+	halfMove     int
+	fullMove     int
+}
+
 //Game a chess game
 type Game struct {
-	board           [rowHight][rowWidth]Piece
-	Moves           []Move     `json:"moves"`
-	White           Player     `json:"white"`
-	Black           Player     `json:"black"`
-	GuildID         string     `json:"gid"`
-	Turn            SideType   `json:"turn"`
-	Winner          SideType   `json:"win"`
-	BoardColorWhite color.RGBA `json:"board_color_white"`
-	BoardColorBlack color.RGBA `json:"board_color_black"`
-}
-
-//GameID Create game id
-func GameID(guild, id1, id2 string) string {
+	position
+	repetitions     map[string]int
+	UID             string      `json:"id"`
+	Moves           []Move      `json:"moves"`
+	White           Player      `json:"white"`
+	Black           Player      `json:"black"`
+	GuildID         string      `json:"gid"`
+	Turn            SideType    `json:"turn"`
+	Result          Result      `json:"result"`
+	BoardColorWhite color.RGBA  `json:"board_color_white"`
+	BoardColorBlack color.RGBA  `json:"board_color_black"`
+	StartFEN        string      `json:"fen,omitempty"`
+	Created         time.Time   `json:"created"`
+	DrawOffer       Offer       `json:"draw_offer"`
+	TakebackRequest Offer       `json:"takeback_request"`
+	TimeControl     TimeControl `json:"time_control"`
+	MoveTimes       []time.Time `json:"move_times"`
+	ChannelID       string      `json:"channel_id"`
+	Reminded        bool        `json:"reminded"`
+	Rated           bool        `json:"rated"`
+	//ClockRestarts when the clock restarted after a takeback by the ply to play
+	ClockRestarts map[int]time.Time `json:"clock_restarts,omitempty"`
+	//Version counts saves so an out of date copy isn't saved over the game
+	Version int `json:"version"`
+	spent   clockSpent
+}
+
+var (
+	//ErrGameOver the game has already finished
+	ErrGameOver = errors.New("the game is already over")
+	//ErrNotAPlayer the player is not playing in the game
+	ErrNotAPlayer = errors.New("you are not playing in this game")
+	//ErrNotYourTurn it's the other players turn
+	ErrNotYourTurn = errors.New("cannot move on enemies turn")
+	//ErrNotYourPiece the piece belongs to the other player
+	ErrNotYourPiece = errors.New("cannot move the other players pieces")
+	//ErrNoPieceAtSource there is no piece on the cell being moved from
+	ErrNoPieceAtSource = errors.New("there is no piece to move")
+)
+
+//PairID identifies two players in a guild whichever order they are given
+func PairID(guild, id1, id2 string) string {
 	ary := []string{id1, id2}
 	sort.Strings(ary)
 	return fmt.Sprintf("%s_%s_%s", guild, ary[0], ary[1])
 }
 
+//NewGameID creates a random game id short enough to type
+func NewGameID() string {
+	buf := make([]byte, gameIDLength/2)
+	if _, err := rand.Read(buf); err != nil {
+		panic(errors.Wrap(err, "unable to create game id"))
+	}
+
+	return hex.EncodeToString(buf)
+}
+
 //ID id
 func (g *Game) ID() string {
-	return GameID(g.GuildID, g.White.ID, g.Black.ID)
+	//Games archived before games had their own id, active games are given
+	//one by db.MigrateGames
+	if g.UID == "" {
+		return PairID(g.GuildID, g.White.ID, g.Black.ID)
+	}
+
+	return g.UID
+}
+
+//PairID the id of the two players in the game
+func (g *Game) PairID() string {
+	return PairID(g.GuildID, g.White.ID, g.Black.ID)
 }
 
 func (g *Game) processMove(move Move) {
 	tmp := g.board[move.From.Row][move.From.Col]
+	//Counters used for the fifty move rule and move numbers
+	if tmp.Kind == PieceTypePawn || g.getAt(move.To).Kind != PieceTypeEmpty {
+		g.halfMove = 0
+	} else {
+		g.halfMove++
+	}
+	if tmp.Side == SideBlack {
+		g.fullMove++
+	}
+
+	g.updateCastlingRights(move)
+	//Castling also moves the rook
+	if tmp.Kind == PieceTypeKing && isCastling(move) {
+		rookMove := castlingRookMove(move)
+		g.board[rookMove.To.Row][rookMove.To.Col] = g.getAt(rookMove.From)
+		g.board[rookMove.From.Row][rookMove.From.Col] = Piece{PieceTypeEmpty, SideEmpty}
+	}
+	//Taking en passant removes the pawn that moved past
+	if tmp.Kind == PieceTypePawn && g.canEnPassant && move.To == g.enPassant {
+		g.board[move.From.Row][move.To.Col] = Piece{PieceTypeEmpty, SideEmpty}
+	}
+	//A pawn moving two cells can be taken en passant on the next move
+	g.canEnPassant = tmp.Kind == PieceTypePawn &&
+		math.Abs(float64(move.To.Row-move.From.Row)) == 2
+	if g.canEnPassant {
+		g.enPassant = Postion{Row: (move.From.Row + move.To.Row) / 2, Col: move.From.Col}
+	}
+
 	g.board[move.From.Row][move.From.Col] = Piece{PieceTypeEmpty, SideEmpty}
 	g.board[move.To.Row][move.To.Col] = tmp
 	//Apply promotion
@@ -289,16 +414,53 @@ func (g *Game) processMove(move Move) {
 	}
 }
 
+//initialPosition returns the position the game started from
+func (g *Game) initialPosition() position {
+	if g.StartFEN == "" {
+		return startPosition
+	}
+
+	pos, _, err := parseFEN(g.StartFEN)
+	if err != nil {
+		return startPosition
+	}
+
+	return pos
+}
+
+//initialTurn returns the side that moved first
+func (g *Game) initialTurn() SideType {
+	if g.StartFEN == "" {
+		return SideWhite
+	}
+
+	_, turn, err := parseFEN(g.StartFEN)
+	if err != nil {
+		return SideWhite
+	}
+
+	return turn
+}
+
 //ProcessMoves process moves
 func (g *Game) ProcessMoves() {
-	g.board = emptyBoard
+	g.position = g.initialPosition()
+	g.repetitions = nil
 
+	turn := g.initialTurn()
+	g.recordPosition(turn)
 	for _, move := range g.Moves {
 		g.processMove(move)
+		turn = turn.other()
+		g.recordPosition(turn)
 	}
+
+	g.replayClock()
 }
 
 func (g *Game) createImgRaw() image.Image {
+	loadAssets()
+
 	boardImgColored := changeColor(
 		boardImg,
 		map[color.Color]color.Color{
@@ -355,7 +517,7 @@ func (g *Game) CreateImage() io.Reader {
 //CreateGif Creates a jif of all the moves
 func (g *Game) CreateGif() io.Reader {
 	//Reset board
-	g.board = emptyBoard
+	g.position = g.initialPosition()
 
 	var tmpMoves []Move
 	//Append empty move so start board state is shown
@@ -409,57 +571,17 @@ func (g *Game) CreateGif() io.Reader {
 	return result
 }
 
-//AlgebraicNotation returns moves in atomic notation
+//AlgebraicNotation returns numbered moves in standard algebraic notation
 func (g *Game) AlgebraicNotation() string {
 	var result strings.Builder
 
-	g.board = emptyBoard
-
-	currentTurn := SideWhite
-	for i, mv := range g.Moves {
-		take := ""
-		if g.getAt(mv.To).Kind != PieceTypeEmpty {
-			take = "x"
+	for i, pair := range g.movePairs() {
+		if i != 0 && i%movePairsPerLine == 0 {
+			result.WriteString("\n")
+		} else if i != 0 {
+			result.WriteString(" ")
 		}
-
-		postion := strings.ToLower(mv.To.String())
-
-		moving := g.getAt(mv.From)
-		pieces := g.findPieces(g.Turn, moving.Kind)
-		//Checks if another piece of the same type can also make the move
-		disambiguating := ""
-		for _, val := range pieces {
-			if val == mv.From {
-				continue
-			}
-
-			err := moves[g.getAt(val).Kind](g, Move{
-				From: val, To: mv.To,
-			})
-			if err == nil {
-				if val.Col == mv.From.Col && val.Row == mv.From.Row {
-					disambiguating = fmt.Sprintf("%s", strings.ToLower(mv.From.String()))
-				} else if val.Col == mv.From.Col {
-					disambiguating = fmt.Sprintf("%s", rankStr(val.Row))
-				} else if val.Row == mv.From.Row {
-					disambiguating = fmt.Sprintf("%s", colStr(val.Col))
-				}
-			}
-		}
-
-		fmt.Fprintf(
-			&result, "%s%s%s%s ",
-			moving.Kind.NotationStr(), disambiguating, take, postion,
-		)
-		if i != 0 && i%3 == 0 {
-			fmt.Fprintf(&result, "\n")
-		}
-		if currentTurn == SideWhite {
-			currentTurn = SideBlack
-		} else {
-			currentTurn = SideWhite
-		}
-		g.processMove(mv)
+		result.WriteString(pair)
 	}
 
 	return result.String()
@@ -473,6 +595,14 @@ func (g *Game) GetPlayer(id string) Player {
 	return g.Black
 }
 
+//GetSidePlayer returns the player playing the side
+func (g *Game) GetSidePlayer(side SideType) Player {
+	if side == SideWhite {
+		return g.White
+	}
+	return g.Black
+}
+
 //GetOpponent GetOpponent
 func (g *Game) GetOpponent(id string) Player {
 	if g.White.ID != id {
@@ -562,20 +692,37 @@ func validPawnMove(g *Game, mv Move) error {
 	pawn := g.getAt(mv.From)
 	target := g.getAt(mv.To)
 
-	if err := g.checkBlockingStraight(mv); err != nil {
-		return err
+	forward := -1
+	if pawn.Side == SideBlack {
+		forward = 1
 	}
 
+	rowMove := mv.To.Row - mv.From.Row
 	moveColD := math.Abs(float64(mv.To.Col - mv.From.Col))
 
+	if rowMove*forward <= 0 {
+		return errors.New("pawns cannot move backwards or to the same cell")
+	}
+
+	if moveColD > 1 {
+		return errors.New("pawns cannot move that far sideways")
+	}
+
 	// Taking another piece
-	if target.Kind == PieceTypeEmpty && mv.From.Col != mv.To.Col && moveColD > 1 {
-		return errors.New("pawns cannot move diagonally without taking a piece")
+	if moveColD == 1 {
+		if rowMove != forward {
+			return errors.New("pawns can only take one cell diagonally")
+		}
+
+		if target.Kind == PieceTypeEmpty && !(g.canEnPassant && mv.To == g.enPassant) {
+			return errors.New("pawns cannot move diagonally without taking a piece")
+		}
+
+		return nil
 	}
 
-	moveD := math.Abs(float64(mv.To.Row - mv.From.Row))
-	if moveD <= 0 {
-		return errors.New("pawns cannot move backwards or to the same cell")
+	if target.Kind != PieceTypeEmpty {
+		return errors.New("pawns cannot take moving forward")
 	}
 
 	var maxMovement float64
@@ -587,16 +734,11 @@ func validPawnMove(g *Game, mv Move) error {
 	}
 
 	//Moving forward
-	if moveD > maxMovement {
+	if math.Abs(float64(rowMove)) > maxMovement {
 		return errors.New("pawns cannot move that far ahead")
 	}
 
-	if (pawn.Side == SideWhite && mv.To.Row >= mv.From.Row) ||
-		(pawn.Side == SideBlack && mv.To.Row <= mv.From.Row) {
-		return errors.New("pawns cannot take one step back")
-	}
-
-	return nil
+	return g.checkRoute(mv)
 }
 
 func validBishopMove(g *Game, mv Move) error {
@@ -633,6 +775,10 @@ func validKingMove(g *Game, mv Move) error {
 	colMoveD := math.Abs(float64(mv.To.Col - mv.From.Col))
 	rowMoveD := math.Abs(float64(mv.To.Row - mv.From.Row))
 
+	if isCastling(mv) {
+		return g.validCastling(mv)
+	}
+
 	if colMoveD > 1 || rowMoveD > 1 {
 		return errors.New("king cannot move more then one cell")
 	}
@@ -666,82 +812,71 @@ func (g *Game) getPiecesForSide(side SideType) []Postion {
 }
 
 func (g *Game) sideInCheck(side SideType) error {
-	kingPos := g.findPieces(side, PieceTypeKing)[0]
-	other := g.getPiecesForSide(g.getAt(kingPos).Side.other())
-
-	for _, val := range other {
-		err := moves[g.getAt(val).Kind](g, Move{
-			From: val,
-			To:   kingPos,
-		})
-		if err == nil {
-			return errors.Errorf("%s king is in check after move", side.String())
-		}
+	king := g.kingPostion(side)
+	if !onBoard(king) {
+		return errors.Errorf("%s has no king", side.String())
+	}
+
+	if g.attackedBy(king, side.other()) {
+		return errors.Errorf("%s king is in check after move", side.String())
 	}
 
 	return nil
 }
 
-//ValidMove returns an error if the move is not valid
-func (g *Game) ValidMove(id string, mv Move) error {
-	// if g.GetPlayer(id).Side != g.Turn {
-	// 	return errors.New("cannot move on enemies turn")
-	// }
-
-	piece := g.board[mv.From.Row][mv.From.Col]
-	// if piece.Side != g.GetPlayer(id).Side {
-	// 	return errors.New("cannot move the other players pieces")
-	// }
-
-	target := g.getAt(mv.To)
-
-	if target.Side == piece.Side {
-		return errors.New("cannot kill a comrade with a move")
+//CanMove returns an error if the player cannot make a move right now
+func (g *Game) CanMove(id string) error {
+	if g.Over() {
+		return ErrGameOver
 	}
 
-	if err := moves[piece.Kind](g, mv); err != nil {
-		return err
+	if g.White.ID != id && g.Black.ID != id {
+		return ErrNotAPlayer
 	}
 
-	//Make move check if king is in check
-	g.MakeMove(mv)
-	defer func(g *Game) {
-		//Remove added move and undo move
-		g.Moves = g.Moves[0 : len(g.Moves)-1]
-		g.board = emptyBoard
-		g.ProcessMoves()
-	}(g)
-
-	if err := g.sideInCheck(g.Turn); err != nil {
-		return err
+	if g.GetPlayer(id).Side != g.Turn {
+		return ErrNotYourTurn
 	}
 
 	return nil
 }
 
-//FindEmptySqaure This is used for one hell of  a hack
-func (g *Game) FindEmptySqaure() Postion {
-	for i := range g.board {
-		for j := range g.board[i] {
-			if g.board[i][j].Kind == PieceTypeEmpty {
-				return Postion{i, j}
-			}
-		}
+//ValidMove returns an error if the move is not valid
+func (g *Game) ValidMove(id string, mv Move) error {
+	if err := g.CanMove(id); err != nil {
+		return err
+	}
+
+	piece := g.getAt(mv.From)
+	if piece.Kind != PieceTypeEmpty && piece.Side != g.GetPlayer(id).Side {
+		return ErrNotYourPiece
 	}
 
-	return Postion{0, 0}
+	return g.legalMove(mv)
 }
 
 //MakeMove move
 func (g *Game) MakeMove(mv Move) {
+	g.MakeMoveAt(mv, time.Now().UTC())
+}
+
+//MakeMoveAt makes a move that was played at the given time
+func (g *Game) MakeMoveAt(mv Move, at time.Time) {
+	g.updateClock(g.Turn, at.Sub(g.LastMoveTime()))
 	g.Moves = append(g.Moves, mv)
+	g.MoveTimes = append(g.MoveTimes, at)
+	g.Reminded = false
 	g.processMove(mv)
+	g.withdrawOffers(g.Turn)
 
 	if g.Turn == SideWhite {
 		g.Turn = SideBlack
 	} else {
 		g.Turn = SideWhite
 	}
+
+	g.recordPosition(g.Turn)
+	g.updateResult()
 }
 
 //StringToPostion StringToPostion
@@ -768,6 +903,7 @@ func changeRowSide(row [rowWidth]Piece, side SideType) [rowWidth]Piece {
 //CreateGame CreateGame
 func CreateGame(white, black, guildID string, whiteColor, BlackColor color.RGBA) Game {
 	result := Game{
+		UID: NewGameID(),
 		White: Player{
 			ID:    white,
 			Side:  SideWhite,
@@ -780,6 +916,7 @@ func CreateGame(white, black, guildID string, whiteColor, BlackColor color.RGBA)
 		},
 		GuildID:         guildID,
 		Turn:            SideWhite,
+		Created:         time.Now().UTC(),
 		BoardColorBlack: color.RGBA{0, 0, 0, 255},
 		BoardColorWhite: color.RGBA{255, 255, 255, 255},
 	}
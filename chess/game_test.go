@@ -0,0 +1,70 @@
+package chess
+
+import (
+	"image/color"
+	"testing"
+)
+
+func newTestGame() Game {
+	return CreateGame(
+		"white", "black", "guild",
+		color.RGBA{255, 255, 255, 255}, color.RGBA{0, 0, 0, 255},
+	)
+}
+
+func testMove(from, to string) Move {
+	return Move{From: StringToPostion(from), To: StringToPostion(to)}
+}
+
+func TestValidMoveRejections(t *testing.T) {
+	finished := newTestGame()
+	finished.Result = WinFor(SideBlack, ReasonCheckmate)
+
+	tests := []struct {
+		name string
+		game Game
+		id   string
+		mv   Move
+		want error
+	}{
+		{"game over", finished, "white", testMove("e2", "e4"), ErrGameOver},
+		{"not a player", newTestGame(), "someone", testMove("e2", "e4"), ErrNotAPlayer},
+		{"not your turn", newTestGame(), "black", testMove("e7", "e5"), ErrNotYourTurn},
+		{"not your piece", newTestGame(), "white", testMove("e7", "e5"), ErrNotYourPiece},
+		{"no piece at source", newTestGame(), "white", testMove("e4", "e5"), ErrNoPieceAtSource},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.game.ValidMove(test.id, test.mv); err != test.want {
+				t.Errorf("got %v want %v", err, test.want)
+			}
+		})
+	}
+}
+
+func TestValidMoveAcceptsOwnPieceOnTurn(t *testing.T) {
+	game := newTestGame()
+
+	if err := game.ValidMove("white", testMove("e2", "e4")); err != nil {
+		t.Fatalf("white e2 e4 rejected: %v", err)
+	}
+	game.MakeMove(testMove("e2", "e4"))
+
+	if err := game.ValidMove("black", testMove("e7", "e5")); err != nil {
+		t.Fatalf("black e7 e5 rejected: %v", err)
+	}
+}
+
+func TestGameIDs(t *testing.T) {
+	first := CreateGame("1", "2", "guild", color.RGBA{}, color.RGBA{})
+	second := CreateGame("2", "1", "guild", color.RGBA{}, color.RGBA{})
+
+	if first.ID() == second.ID() || len(first.ID()) != gameIDLength {
+		t.Errorf("game ids %s and %s should be unique", first.ID(), second.ID())
+	}
+
+	if first.PairID() != second.PairID() || first.PairID() != PairID("guild", "1", "2") {
+		t.Errorf("pair ids %s and %s should match", first.PairID(), second.PairID())
+	}
+}
@@ -0,0 +1,85 @@
+package chess
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+)
+
+const (
+	cellSize   = 126
+	boardInset = 84
+
+	arrowWidth      = 22
+	arrowHeadLength = 56
+	arrowHeadWidth  = 64
+)
+
+//arrowColor the colour of hint arrows
+var arrowColor = color.RGBA{21, 120, 27, 200}
+
+//cellCentre the pixel at the middle of a cell on the board image
+func cellCentre(pos Postion) (float64, float64) {
+	return float64(boardInset + pos.Col*cellSize + cellSize/2),
+		float64(boardInset + pos.Row*cellSize + cellSize/2)
+}
+
+//arrowMask the pixels covered by an arrow from the middle of one cell to
+//the middle of another
+func arrowMask(bounds image.Rectangle, mv Move) *image.Alpha {
+	mask := image.NewAlpha(bounds)
+
+	x0, y0 := cellCentre(mv.From)
+	x1, y1 := cellCentre(mv.To)
+	length := math.Hypot(x1-x0, y1-y0)
+	if length == 0 {
+		return mask
+	}
+	dirX, dirY := (x1-x0)/length, (y1-y0)/length
+
+	area := image.Rect(
+		int(math.Min(x0, x1))-arrowHeadWidth, int(math.Min(y0, y1))-arrowHeadWidth,
+		int(math.Max(x0, x1))+arrowHeadWidth, int(math.Max(y0, y1))+arrowHeadWidth,
+	).Intersect(bounds)
+
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			//Distance along the arrow and away from its middle
+			along := (float64(x)-x0)*dirX + (float64(y)-y0)*dirY
+			across := math.Abs((float64(x)-x0)*dirY - (float64(y)-y0)*dirX)
+
+			inShaft := along >= 0 && along <= length-arrowHeadLength && across <= arrowWidth/2
+			inHead := along > length-arrowHeadLength && along <= length &&
+				across <= arrowHeadWidth/2*(length-along)/arrowHeadLength
+			if inShaft || inHead {
+				mask.SetAlpha(x, y, color.Alpha{255})
+			}
+		}
+	}
+
+	return mask
+}
+
+//createHintImgRaw draws the board with an arrow showing the move
+func (g *Game) createHintImgRaw(mv Move) image.Image {
+	board := g.createImgRaw()
+
+	b := board.Bounds()
+	snapshot := image.NewRGBA(b)
+	draw.Draw(snapshot, b, board, image.ZP, draw.Src)
+	draw.DrawMask(snapshot, b, &image.Uniform{arrowColor}, image.ZP, arrowMask(b, mv), b.Min, draw.Over)
+
+	return snapshot
+}
+
+//CreateHintImage Creates a png of the board with an arrow showing the move
+func (g *Game) CreateHintImage(mv Move) io.Reader {
+	result := &bytes.Buffer{}
+
+	png.Encode(result, g.createHintImgRaw(mv))
+	return result
+}
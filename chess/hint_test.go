@@ -0,0 +1,34 @@
+package chess
+
+import (
+	"image"
+	"testing"
+)
+
+func TestArrowMask(t *testing.T) {
+	bounds := image.Rect(0, 0, 2*boardInset+8*cellSize, 2*boardInset+8*cellSize)
+	mask := arrowMask(bounds, Move{From: Postion{Row: 6, Col: 4}, To: Postion{Row: 4, Col: 6}})
+
+	covered := func(pos Postion) bool {
+		x, y := cellCentre(pos)
+		return mask.AlphaAt(int(x), int(y)).A != 0
+	}
+
+	for _, pos := range []Postion{{Row: 6, Col: 4}, {Row: 5, Col: 5}} {
+		if !covered(pos) {
+			t.Errorf("arrow misses %v", pos)
+		}
+	}
+
+	for _, pos := range []Postion{{Row: 0, Col: 0}, {Row: 6, Col: 6}, {Row: 4, Col: 4}} {
+		if covered(pos) {
+			t.Errorf("arrow covers %v", pos)
+		}
+	}
+
+	//The point of the arrow ends on the target cell
+	x, y := cellCentre(Postion{Row: 4, Col: 6})
+	if mask.AlphaAt(int(x)-4, int(y)+4).A == 0 || mask.AlphaAt(int(x)+4, int(y)-4).A != 0 {
+		t.Error("arrow head isn't on the target cell")
+	}
+}
@@ -0,0 +1,349 @@
+package chess
+
+import (
+	"github.com/pkg/errors"
+)
+
+var promotionPieces = []PieceType{
+	PieceTypeQueen, PieceTypeRook, PieceTypeBishop, PieceTypeKnight,
+}
+
+var (
+	knightSteps = []Postion{{-2, -1}, {-2, 1}, {-1, -2}, {-1, 2}, {1, -2}, {1, 2}, {2, -1}, {2, 1}}
+	kingSteps   = []Postion{{-1, -1}, {-1, 0}, {-1, 1}, {0, -1}, {0, 1}, {1, -1}, {1, 0}, {1, 1}}
+	bishopSteps = []Postion{{-1, -1}, {-1, 1}, {1, -1}, {1, 1}}
+	rookSteps   = []Postion{{-1, 0}, {1, 0}, {0, -1}, {0, 1}}
+)
+
+func onBoard(pos Postion) bool {
+	return pos.Row >= 0 && pos.Row < rowHight && pos.Col >= 0 && pos.Col < rowWidth
+}
+
+//forward the row direction the side's pawns move
+func forward(side SideType) int {
+	if side == SideBlack {
+		return 1
+	}
+
+	return -1
+}
+
+//attackedBy returns true if any piece of the side attacks the postion
+func (p *position) attackedBy(pos Postion, side SideType) bool {
+	is := func(at Postion, kinds ...PieceType) bool {
+		if !onBoard(at) {
+			return false
+		}
+		piece := p.board[at.Row][at.Col]
+		if piece.Side != side {
+			return false
+		}
+		for _, kind := range kinds {
+			if piece.Kind == kind {
+				return true
+			}
+		}
+		return false
+	}
+
+	//Pawns attack towards the other side so look back from the postion
+	pawnRow := pos.Row - forward(side)
+	if is(Postion{pawnRow, pos.Col - 1}, PieceTypePawn) || is(Postion{pawnRow, pos.Col + 1}, PieceTypePawn) {
+		return true
+	}
+
+	for _, step := range knightSteps {
+		if is(Postion{pos.Row + step.Row, pos.Col + step.Col}, PieceTypeKnight) {
+			return true
+		}
+	}
+
+	for _, step := range kingSteps {
+		if is(Postion{pos.Row + step.Row, pos.Col + step.Col}, PieceTypeKing) {
+			return true
+		}
+	}
+
+	slides := func(steps []Postion, kinds ...PieceType) bool {
+		for _, step := range steps {
+			at := Postion{pos.Row + step.Row, pos.Col + step.Col}
+			for onBoard(at) && p.board[at.Row][at.Col].Kind == PieceTypeEmpty {
+				at = Postion{at.Row + step.Row, at.Col + step.Col}
+			}
+			if is(at, kinds...) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return slides(bishopSteps, PieceTypeBishop, PieceTypeQueen) ||
+		slides(rookSteps, PieceTypeRook, PieceTypeQueen)
+}
+
+//kingPostion finds the side's king
+func (p *position) kingPostion(side SideType) Postion {
+	for r := 0; r < rowHight; r++ {
+		for c := 0; c < rowWidth; c++ {
+			if piece := p.board[r][c]; piece.Kind == PieceTypeKing && piece.Side == side {
+				return Postion{r, c}
+			}
+		}
+	}
+
+	return Postion{-1, -1}
+}
+
+//inCheck returns true if the side's king is attacked
+func (p *position) inCheck(side SideType) bool {
+	king := p.kingPostion(side)
+	return onBoard(king) && p.attackedBy(king, side.other())
+}
+
+//addPawnMove adds a pawn move with every promotion if it reaches the end
+func addPawnMove(result []Move, side SideType, from, to Postion) []Move {
+	if to.Row != homeRow(side.other()) {
+		return append(result, Move{From: from, To: to})
+	}
+
+	for _, promo := range promotionPieces {
+		result = append(result, Move{From: from, To: to, Promotion: promo})
+	}
+
+	return result
+}
+
+//pseudoMoves returns the side's moves ignoring whether they leave its king in check
+func (p *position) pseudoMoves(side SideType, capturesOnly bool) []Move {
+	result := make([]Move, 0, 48)
+
+	for r := 0; r < rowHight; r++ {
+		for c := 0; c < rowWidth; c++ {
+			if p.board[r][c].Side == side {
+				result = p.pieceMoves(result, Postion{r, c}, capturesOnly)
+			}
+		}
+	}
+
+	return result
+}
+
+//pieceMoves adds the moves of the piece at the postion ignoring whether
+//they leave its king in check
+func (p *position) pieceMoves(result []Move, from Postion, capturesOnly bool) []Move {
+	piece := p.board[from.Row][from.Col]
+
+	switch piece.Kind {
+	case PieceTypePawn:
+		result = p.pawnMoves(result, piece.Side, from, capturesOnly)
+	case PieceTypeKnight:
+		result = p.stepMoves(result, piece.Side, from, knightSteps, false, capturesOnly)
+	case PieceTypeBishop:
+		result = p.stepMoves(result, piece.Side, from, bishopSteps, true, capturesOnly)
+	case PieceTypeRook:
+		result = p.stepMoves(result, piece.Side, from, rookSteps, true, capturesOnly)
+	case PieceTypeQueen:
+		result = p.stepMoves(result, piece.Side, from, bishopSteps, true, capturesOnly)
+		result = p.stepMoves(result, piece.Side, from, rookSteps, true, capturesOnly)
+	case PieceTypeKing:
+		result = p.stepMoves(result, piece.Side, from, kingSteps, false, capturesOnly)
+		if !capturesOnly {
+			result = p.castlingMoves(result, piece.Side, from)
+		}
+	}
+
+	return result
+}
+
+func (p *position) pawnMoves(result []Move, side SideType, from Postion, capturesOnly bool) []Move {
+	dir := forward(side)
+	one := Postion{from.Row + dir, from.Col}
+	if !onBoard(one) {
+		return result
+	}
+
+	//Promoting is worth looking at even when only looking at captures
+	promotes := one.Row == homeRow(side.other())
+	if p.board[one.Row][one.Col].Kind == PieceTypeEmpty && (!capturesOnly || promotes) {
+		result = addPawnMove(result, side, from, one)
+
+		two := Postion{from.Row + 2*dir, from.Col}
+		if !capturesOnly && from.Row == homeRow(side)+dir && p.board[two.Row][two.Col].Kind == PieceTypeEmpty {
+			result = append(result, Move{From: from, To: two})
+		}
+	}
+
+	for _, col := range []int{from.Col - 1, from.Col + 1} {
+		to := Postion{one.Row, col}
+		if !onBoard(to) {
+			continue
+		}
+
+		if p.board[to.Row][to.Col].Side == side.other() || (p.canEnPassant && to == p.enPassant) {
+			result = addPawnMove(result, side, from, to)
+		}
+	}
+
+	return result
+}
+
+//stepMoves adds moves in each direction, sliding pieces keep going until blocked
+func (p *position) stepMoves(
+	result []Move, side SideType, from Postion, steps []Postion, slides, capturesOnly bool,
+) []Move {
+	for _, step := range steps {
+		to := Postion{from.Row + step.Row, from.Col + step.Col}
+		for onBoard(to) {
+			target := p.board[to.Row][to.Col]
+			if target.Side == side {
+				break
+			}
+
+			if target.Kind != PieceTypeEmpty || !capturesOnly {
+				result = append(result, Move{From: from, To: to})
+			}
+
+			if target.Kind != PieceTypeEmpty || !slides {
+				break
+			}
+			to = Postion{to.Row + step.Row, to.Col + step.Col}
+		}
+	}
+
+	return result
+}
+
+func (p *position) castlingMoves(result []Move, side SideType, from Postion) []Move {
+	for _, kingSide := range []bool{true, false} {
+		if mv := CastlingMove(side, kingSide); from == mv.From && p.castlingError(side, kingSide) == nil {
+			result = append(result, mv)
+		}
+	}
+
+	return result
+}
+
+//safeMove tries the move then puts the board back, returns false if it
+//leaves the mover's king in check
+func (g *Game) safeMove(mv Move) bool {
+	side := g.getAt(mv.From).Side
+
+	prev := g.position
+	g.processMove(mv)
+	safe := !g.inCheck(side)
+	g.position = prev
+
+	return safe
+}
+
+//legalMove checks the move is one the piece can make and that it doesn't
+//leave its own king in check
+func (g *Game) legalMove(mv Move) error {
+	piece := g.getAt(mv.From)
+	if piece.Kind == PieceTypeEmpty {
+		return ErrNoPieceAtSource
+	}
+
+	found := false
+	for _, pieceMove := range g.pieceMoves(nil, mv.From, false) {
+		if pieceMove == mv {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return g.illegalMoveReason(piece, mv)
+	}
+
+	if !g.safeMove(mv) {
+		return errors.Errorf("%s king is in check after move", piece.Side.String())
+	}
+
+	return nil
+}
+
+//illegalMoveReason explains why the piece can't make the move
+func (g *Game) illegalMoveReason(piece Piece, mv Move) error {
+	if g.getAt(mv.To).Side == piece.Side {
+		return errors.New("cannot kill a comrade with a move")
+	}
+
+	if err := moves[piece.Kind](g, mv); err != nil {
+		return err
+	}
+
+	if err := validPromotion(piece, mv); err != nil {
+		return err
+	}
+
+	return errors.Errorf("%s cannot move to %s", piece.Kind.String(), mv.To.String())
+}
+
+//validPromotion checks pawns reaching the other end pick what they become
+//and that nothing else is promoted
+func validPromotion(piece Piece, mv Move) error {
+	if piece.Kind != PieceTypePawn || mv.To.Row != homeRow(piece.Side.other()) {
+		if mv.Promotion != PieceTypeEmpty {
+			return errors.New("only pawns reaching the other end can be promoted")
+		}
+		return nil
+	}
+
+	for _, promo := range promotionPieces {
+		if mv.Promotion == promo {
+			return nil
+		}
+	}
+
+	return errors.New("pawns reaching the other end must be promoted to a queen, rook, bishop or knight")
+}
+
+//LegalMoves returns every legal move for the side whose turn it is
+func (g *Game) LegalMoves() []Move {
+	result := []Move{}
+	for _, mv := range g.pseudoMoves(g.Turn, false) {
+		if g.safeMove(mv) {
+			result = append(result, mv)
+		}
+	}
+
+	return result
+}
+
+//LegalMovesFrom returns every legal move for the piece at the postion
+func (g *Game) LegalMovesFrom(from Postion) []Move {
+	result := []Move{}
+	for _, mv := range g.LegalMoves() {
+		if mv.From == from {
+			result = append(result, mv)
+		}
+	}
+
+	return result
+}
+
+//hasLegalMove returns true if the side to move has any legal move
+func (g *Game) hasLegalMove() bool {
+	for _, mv := range g.pseudoMoves(g.Turn, false) {
+		if g.safeMove(mv) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//InCheck returns true if the side to move is in check
+func (g *Game) InCheck() bool {
+	return g.sideInCheck(g.Turn) != nil
+}
+
+//Checkmate returns true if the side to move has been checkmated
+func (g *Game) Checkmate() bool {
+	return g.InCheck() && !g.hasLegalMove()
+}
+
+//Stalemate returns true if the side to move has no legal moves but is not in check
+func (g *Game) Stalemate() bool {
+	return !g.InCheck() && !g.hasLegalMove()
+}
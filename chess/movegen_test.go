@@ -0,0 +1,196 @@
+package chess
+
+import (
+	"testing"
+)
+
+//perft counts the move paths depth plies deep
+func perft(g *Game, depth int) int {
+	moves := g.LegalMoves()
+	if depth == 1 {
+		return len(moves)
+	}
+
+	nodes := 0
+	for _, mv := range moves {
+		prev, turn := g.position, g.Turn
+		g.processMove(mv)
+		g.Turn = turn.other()
+		nodes += perft(g, depth-1)
+		g.position, g.Turn = prev, turn
+	}
+
+	return nodes
+}
+
+func TestPerft(t *testing.T) {
+	tests := []struct {
+		name  string
+		fen   string
+		nodes []int
+	}{
+		{"start", StartingFEN, []int{20, 400, 8902}},
+		{"kiwipete", "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", []int{48, 2039, 97862}},
+		{"en passant", "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", []int{14, 191, 2812}},
+		{"promotion", "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", []int{6, 264, 9467}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			game, err := FromFEN(test.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i, want := range test.nodes {
+				if testing.Short() && want > 10000 {
+					break
+				}
+				if got := perft(&game, i+1); got != want {
+					t.Errorf("depth %d got %d want %d", i+1, got, want)
+				}
+			}
+		})
+	}
+}
+
+//hasMove returns true if the UCI move is one of the legal moves
+func hasMove(g *Game, uci string) bool {
+	for _, mv := range g.LegalMoves() {
+		if mv.UCI() == uci {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestCastlingRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		fen   string
+		score string
+		legal []string
+		not   []string
+	}{
+		{"both ways", "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "", []string{"e1g1", "e1c1"}, nil},
+		{"through check", "r3k2r/8/8/8/8/8/5r2/R3K2R w KQkq - 0 1", "", []string{"e1c1"}, []string{"e1g1"}},
+		{"into check", "r3k2r/8/8/8/8/8/6r1/R3K2R w KQkq - 0 1", "", []string{"e1c1"}, []string{"e1g1"}},
+		{"out of check", "r3k2r/8/8/8/8/8/4r3/R3K2R w KQkq - 0 1", "", nil, []string{"e1g1", "e1c1"}},
+		{"queen side b file attacked", "r3k2r/8/8/8/8/8/1r6/R3K2R w KQkq - 0 1", "", []string{"e1c1", "e1g1"}, nil},
+		{"blocked", "r3k2r/8/8/8/8/8/8/RN2K1NR w KQkq - 0 1", "", nil, []string{"e1g1", "e1c1"}},
+		{"after the rook moves back", "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "Rh2 Ra7 Rh1 Ra8", []string{"e1c1"}, []string{"e1g1"}},
+		{"after the king moves back", "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "Kf1 Kf8 Ke1 Ke8", nil, []string{"e1g1", "e1c1"}},
+		{"after the rook is taken", "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "Rxh8+ Kd7", []string{"e1c1"}, []string{"e1g1"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			game, err := FromFEN(test.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			playSAN(t, &game, test.score)
+
+			for _, uci := range test.legal {
+				if !hasMove(&game, uci) {
+					t.Errorf("%s should be legal", uci)
+				}
+			}
+			for _, uci := range test.not {
+				if hasMove(&game, uci) {
+					t.Errorf("%s should not be legal", uci)
+				}
+			}
+		})
+	}
+}
+
+func TestEnPassant(t *testing.T) {
+	game, err := FromFEN("4k3/2p5/8/1P6/8/8/8/4K3 b - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	playSAN(t, &game, "c5")
+	if !hasMove(&game, "b5c6") {
+		t.Fatal("b5c6 en passant should be legal straight after c5")
+	}
+
+	playSAN(t, &game, "bxc6")
+	if got := game.getAt(StringToPostion("c5")); got.Kind != PieceTypeEmpty {
+		t.Errorf("the captured pawn is still on c5 %v", got)
+	}
+
+	//The chance is gone once another move is made
+	game, _ = FromFEN("4k3/2p5/8/1P6/8/8/8/4K3 b - - 0 1")
+	playSAN(t, &game, "c5 Kd1 Kd8")
+	if hasMove(&game, "b5c6") {
+		t.Error("en passant allowed a move late")
+	}
+}
+
+func TestEnPassantDiscoveredCheck(t *testing.T) {
+	//Taking removes both pawns from the fifth rank exposing the king to the rook
+	game, err := FromFEN("8/8/8/KPp4r/8/8/8/7k w - c6 0 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hasMove(&game, "b5c6") {
+		t.Error("en passant that exposes the king should be illegal")
+	}
+	if err := game.ValidMove(game.White.ID, testMove("b5", "c6")); err == nil {
+		t.Error("ValidMove accepted en passant into check")
+	}
+
+	game, err = FromFEN("8/8/8/KPp5/8/8/8/7k w - c6 0 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasMove(&game, "b5c6") {
+		t.Error("en passant without the rook should be legal")
+	}
+}
+
+func TestPromotionRules(t *testing.T) {
+	game, err := FromFEN("1n6/4P3/8/8/8/8/3P4/k6K w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		mv    Move
+		legal bool
+	}{
+		{"missing", testMove("e7", "e8"), false},
+		{"queen", Move{From: StringToPostion("e7"), To: StringToPostion("e8"), Promotion: PieceTypeQueen}, true},
+		{"knight", Move{From: StringToPostion("e7"), To: StringToPostion("e8"), Promotion: PieceTypeKnight}, true},
+		{"to a king", Move{From: StringToPostion("e7"), To: StringToPostion("e8"), Promotion: PieceTypeKing}, false},
+		{"to a pawn", Move{From: StringToPostion("e7"), To: StringToPostion("e8"), Promotion: PieceTypePawn}, false},
+		{"diagonal onto an empty cell", Move{From: StringToPostion("e7"), To: StringToPostion("d8"), Promotion: PieceTypeQueen}, false},
+		{"misplaced on a pawn move", Move{From: StringToPostion("d2"), To: StringToPostion("d4"), Promotion: PieceTypeQueen}, false},
+		{"misplaced on a king move", Move{From: StringToPostion("h1"), To: StringToPostion("g1"), Promotion: PieceTypeQueen}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := game.ValidMove(game.White.ID, test.mv)
+			if (err == nil) != test.legal {
+				t.Errorf("%s got %v", test.mv.UCI(), err)
+			}
+		})
+	}
+
+	promotions := 0
+	for _, mv := range game.LegalMovesFrom(StringToPostion("e7")) {
+		if mv.Promotion == PieceTypeEmpty {
+			t.Errorf("%s is a legal move without a promotion", mv.UCI())
+		}
+		promotions++
+	}
+	if promotions != 4 {
+		t.Errorf("got %d promotions want 4", promotions)
+	}
+}
@@ -0,0 +1,195 @@
+package chess
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	//ErrDrawOffered the player already has a draw offer waiting
+	ErrDrawOffered = errors.New("you have already offered a draw")
+	//ErrNoDrawOffer the opponent hasn't offered a draw
+	ErrNoDrawOffer = errors.New("your opponent hasn't offered a draw")
+	//ErrNoMoveToTakeBack the player hasn't made a move yet
+	ErrNoMoveToTakeBack = errors.New("you haven't made a move to take back")
+	//ErrTakebackRequested the player already has a takeback request waiting
+	ErrTakebackRequested = errors.New("you have already asked for a takeback")
+	//ErrNoTakeback the opponent hasn't asked for a takeback
+	ErrNoTakeback = errors.New("your opponent hasn't asked for a takeback")
+)
+
+//Offer a proposal from one player waiting on the other player
+type Offer struct {
+	Side SideType `json:"side"`
+	//Ply the number of moves made when the offer was made
+	Ply int `json:"ply"`
+}
+
+//Pending returns true if the offer hasn't been answered yet
+func (o Offer) Pending() bool {
+	return o.Side != SideEmpty
+}
+
+//offerFrom returns the side of the player making an offer
+func (g *Game) offerFrom(id string) (SideType, error) {
+	if g.Over() {
+		return SideEmpty, ErrGameOver
+	}
+
+	if g.White.ID != id && g.Black.ID != id {
+		return SideEmpty, ErrNotAPlayer
+	}
+
+	return g.GetPlayer(id).Side, nil
+}
+
+//OfferDraw offers the player's opponent a draw
+func (g *Game) OfferDraw(id string) error {
+	side, err := g.offerFrom(id)
+	if err != nil {
+		return err
+	}
+
+	if g.DrawOffer.Side == side {
+		return ErrDrawOffered
+	}
+
+	g.DrawOffer = Offer{Side: side, Ply: len(g.Moves)}
+	return nil
+}
+
+//AcceptDraw ends the game as a draw if the opponent offered one
+func (g *Game) AcceptDraw(id string) error {
+	side, err := g.offerFrom(id)
+	if err != nil {
+		return err
+	}
+
+	if g.DrawOffer.Side != side.other() {
+		return ErrNoDrawOffer
+	}
+
+	g.DrawOffer = Offer{}
+	g.Result = DrawBy(ReasonAgreement)
+	return nil
+}
+
+//DeclineDraw turns down the opponent's draw offer
+func (g *Game) DeclineDraw(id string) error {
+	side, err := g.offerFrom(id)
+	if err != nil {
+		return err
+	}
+
+	if g.DrawOffer.Side != side.other() {
+		return ErrNoDrawOffer
+	}
+
+	g.DrawOffer = Offer{}
+	return nil
+}
+
+//takebackPlies returns how many moves to undo to take back the side's last move
+func (g *Game) takebackPlies(side SideType) int {
+	plies := 1
+	if g.Turn == side {
+		//The opponent has already replied
+		plies = 2
+	}
+
+	if plies > len(g.Moves) {
+		return 0
+	}
+
+	return plies
+}
+
+//RequestTakeback asks the opponent to let the player take back their last move
+func (g *Game) RequestTakeback(id string) error {
+	side, err := g.offerFrom(id)
+	if err != nil {
+		return err
+	}
+
+	if g.TakebackRequest.Side == side {
+		return ErrTakebackRequested
+	}
+
+	if g.takebackPlies(side) == 0 {
+		return ErrNoMoveToTakeBack
+	}
+
+	g.TakebackRequest = Offer{Side: side, Ply: len(g.Moves)}
+	return nil
+}
+
+//AcceptTakeback undoes the opponent's last move and any reply to it
+func (g *Game) AcceptTakeback(id string) error {
+	return g.AcceptTakebackAt(id, time.Now().UTC())
+}
+
+//AcceptTakebackAt undoes the opponent's last move and any reply to it
+//restarting the clock of the side to move at the given time
+func (g *Game) AcceptTakebackAt(id string, at time.Time) error {
+	side, err := g.offerFrom(id)
+	if err != nil {
+		return err
+	}
+
+	if g.TakebackRequest.Side != side.other() {
+		return ErrNoTakeback
+	}
+
+	plies := g.takebackPlies(g.TakebackRequest.Side)
+	g.Moves = g.Moves[:len(g.Moves)-plies]
+	if len(g.MoveTimes) > len(g.Moves) {
+		g.MoveTimes = g.MoveTimes[:len(g.Moves)]
+	}
+	if plies%2 == 1 {
+		g.Turn = g.Turn.other()
+	}
+
+	//The side to move shouldn't be charged for the moves taken back
+	for ply := range g.ClockRestarts {
+		if ply >= len(g.Moves) {
+			delete(g.ClockRestarts, ply)
+		}
+	}
+	if g.ClockRestarts == nil {
+		g.ClockRestarts = make(map[int]time.Time)
+	}
+	g.ClockRestarts[len(g.Moves)] = at
+	g.Reminded = false
+	g.ProcessMoves()
+
+	g.TakebackRequest = Offer{}
+	g.DrawOffer = Offer{}
+	return nil
+}
+
+//RejectTakeback turns down the opponent's takeback request
+func (g *Game) RejectTakeback(id string) error {
+	side, err := g.offerFrom(id)
+	if err != nil {
+		return err
+	}
+
+	if g.TakebackRequest.Side != side.other() {
+		return ErrNoTakeback
+	}
+
+	g.TakebackRequest = Offer{}
+	return nil
+}
+
+//withdrawOffers drops offers the last move made out of date
+func (g *Game) withdrawOffers(side SideType) {
+	//An offer made on your own turn goes with the move
+	if g.DrawOffer.Side == side && g.DrawOffer.Ply != len(g.Moves)-1 {
+		g.DrawOffer = Offer{}
+	}
+
+	//Any move changes which move would be taken back
+	g.TakebackRequest = Offer{}
+}
@@ -0,0 +1,121 @@
+package chess
+
+import "testing"
+
+func TestDrawOffer(t *testing.T) {
+	game := newTestGame()
+
+	if err := game.AcceptDraw(game.Black.ID); err != ErrNoDrawOffer {
+		t.Errorf("accepting without an offer got %v", err)
+	}
+
+	if err := game.OfferDraw(game.White.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := game.OfferDraw(game.White.ID); err != ErrDrawOffered {
+		t.Errorf("offering twice got %v", err)
+	}
+	if err := game.AcceptDraw(game.White.ID); err != ErrNoDrawOffer {
+		t.Errorf("accepting own offer got %v", err)
+	}
+
+	if err := game.AcceptDraw(game.Black.ID); err != nil {
+		t.Fatal(err)
+	}
+	if game.Result != DrawBy(ReasonAgreement) {
+		t.Errorf("got %+v want a draw by agreement", game.Result)
+	}
+}
+
+func TestDrawOfferWithdrawnByMove(t *testing.T) {
+	game := newTestGame()
+
+	//Offered with a move so it stays for the reply
+	game.OfferDraw(game.White.ID)
+	playSAN(t, &game, "e4")
+	if !game.DrawOffer.Pending() {
+		t.Fatal("offer made before moving was withdrawn")
+	}
+
+	playSAN(t, &game, "e5")
+	if !game.DrawOffer.Pending() {
+		t.Fatal("offer withdrawn by the opponent moving")
+	}
+
+	playSAN(t, &game, "Nf3")
+	if game.DrawOffer.Pending() {
+		t.Error("offer still pending after the offering player moved again")
+	}
+	if err := game.DeclineDraw(game.Black.ID); err != ErrNoDrawOffer {
+		t.Errorf("declining a withdrawn offer got %v", err)
+	}
+}
+
+func TestTakeback(t *testing.T) {
+	tests := []struct {
+		name      string
+		score     string
+		requester SideType
+		want      string
+	}{
+		{"before reply", "e4 e5 Nf3", SideWhite, "e4 e5"},
+		{"after reply", "e4 e5 Nf3 Nc6", SideWhite, "e4 e5"},
+		{"black", "e4 e5", SideBlack, "e4"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			game := newTestGame()
+			playSAN(t, &game, test.score)
+
+			want := newTestGame()
+			playSAN(t, &want, test.want)
+
+			requester := game.GetSidePlayer(test.requester).ID
+			if err := game.RequestTakeback(requester); err != nil {
+				t.Fatal(err)
+			}
+			if err := game.AcceptTakeback(requester); err != ErrNoTakeback {
+				t.Errorf("accepting own request got %v", err)
+			}
+			if err := game.AcceptTakeback(game.GetOpponent(requester).ID); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(game.Moves) != len(want.Moves) || game.Turn != test.requester {
+				t.Errorf("got %d moves turn %s", len(game.Moves), game.Turn)
+			}
+			if game.FEN() != want.FEN() {
+				t.Errorf("got %s want %s", game.FEN(), want.FEN())
+			}
+		})
+	}
+}
+
+func TestTakebackRejections(t *testing.T) {
+	game := newTestGame()
+
+	if err := game.RequestTakeback(game.White.ID); err != ErrNoMoveToTakeBack {
+		t.Errorf("requesting before moving got %v", err)
+	}
+
+	playSAN(t, &game, "e4")
+	if err := game.RequestTakeback(game.Black.ID); err != ErrNoMoveToTakeBack {
+		t.Errorf("black requesting before moving got %v", err)
+	}
+
+	game.RequestTakeback(game.White.ID)
+	if err := game.RejectTakeback(game.Black.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := game.AcceptTakeback(game.Black.ID); err != ErrNoTakeback {
+		t.Errorf("accepting a rejected request got %v", err)
+	}
+
+	//Moving makes the request out of date
+	game.RequestTakeback(game.White.ID)
+	playSAN(t, &game, "e5")
+	if game.TakebackRequest.Pending() {
+		t.Error("request still pending after a move")
+	}
+}
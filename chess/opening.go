@@ -0,0 +1,100 @@
+package chess
+
+import (
+	"strings"
+)
+
+//Opening a named sequence of moves from the starting position
+type Opening struct {
+	Name  string
+	Moves []string
+}
+
+const (
+	//OpeningCustom the opening of games that didn't start from the usual position
+	OpeningCustom = "Custom position"
+	//OpeningUnknown the opening of games no named opening matches
+	OpeningUnknown = "Unknown opening"
+)
+
+func opening(name, moves string) Opening {
+	return Opening{Name: name, Moves: strings.Fields(moves)}
+}
+
+//openings the openings games are named after, the longest match wins
+var openings = []Opening{
+	opening("King's Pawn Opening", "e4"),
+	opening("King's Pawn Game", "e4 e5"),
+	opening("Ruy Lopez", "e4 e5 Nf3 Nc6 Bb5"),
+	opening("Italian Game", "e4 e5 Nf3 Nc6 Bc4"),
+	opening("Two Knights Defence", "e4 e5 Nf3 Nc6 Bc4 Nf6"),
+	opening("Scotch Game", "e4 e5 Nf3 Nc6 d4"),
+	opening("Four Knights Game", "e4 e5 Nf3 Nc6 Nc3 Nf6"),
+	opening("Petrov's Defence", "e4 e5 Nf3 Nf6"),
+	opening("Philidor Defence", "e4 e5 Nf3 d6"),
+	opening("King's Gambit", "e4 e5 f4"),
+	opening("Vienna Game", "e4 e5 Nc3"),
+	opening("Bishop's Opening", "e4 e5 Bc4"),
+	opening("Sicilian Defence", "e4 c5"),
+	opening("Sicilian Defence, Najdorf Variation", "e4 c5 Nf3 d6 d4 cxd4 Nxd4 Nf6 Nc3 a6"),
+	opening("Sicilian Defence, Dragon Variation", "e4 c5 Nf3 d6 d4 cxd4 Nxd4 Nf6 Nc3 g6"),
+	opening("Sicilian Defence, Alapin Variation", "e4 c5 c3"),
+	opening("French Defence", "e4 e6"),
+	opening("Caro-Kann Defence", "e4 c6"),
+	opening("Pirc Defence", "e4 d6"),
+	opening("Scandinavian Defence", "e4 d5"),
+	opening("Alekhine's Defence", "e4 Nf6"),
+	opening("Modern Defence", "e4 g6"),
+	opening("Queen's Pawn Opening", "d4"),
+	opening("Queen's Pawn Game", "d4 d5"),
+	opening("Queen's Gambit", "d4 d5 c4"),
+	opening("Queen's Gambit Accepted", "d4 d5 c4 dxc4"),
+	opening("Queen's Gambit Declined", "d4 d5 c4 e6"),
+	opening("Slav Defence", "d4 d5 c4 c6"),
+	opening("London System", "d4 d5 Bf4"),
+	opening("Indian Defence", "d4 Nf6"),
+	opening("London System", "d4 Nf6 Bf4"),
+	opening("King's Indian Defence", "d4 Nf6 c4 g6"),
+	opening("Grünfeld Defence", "d4 Nf6 c4 g6 Nc3 d5"),
+	opening("Nimzo-Indian Defence", "d4 Nf6 c4 e6 Nc3 Bb4"),
+	opening("Queen's Indian Defence", "d4 Nf6 c4 e6 Nf3 b6"),
+	opening("Dutch Defence", "d4 f5"),
+	opening("English Opening", "c4"),
+	opening("Réti Opening", "Nf3"),
+	opening("Bird's Opening", "f4"),
+	opening("Larsen's Opening", "b3"),
+}
+
+//Opening names the opening the game started with
+func (g *Game) Opening() string {
+	if g.StartFEN != "" && g.StartFEN != StartingFEN {
+		return OpeningCustom
+	}
+
+	played := g.SANMoves()
+	for i := range played {
+		played[i] = strings.TrimRight(played[i], "+#")
+	}
+
+	result := OpeningUnknown
+	longest := 0
+	for _, opening := range openings {
+		if len(opening.Moves) <= longest || len(opening.Moves) > len(played) {
+			continue
+		}
+
+		match := true
+		for i, mv := range opening.Moves {
+			if played[i] != mv {
+				match = false
+				break
+			}
+		}
+
+		if match {
+			result, longest = opening.Name, len(opening.Moves)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,236 @@
+package chess
+
+import (
+	"fmt"
+	"image/color"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+//pgnLineWidth PGN export lines must not be longer than this
+const pgnLineWidth = 80
+
+//PGNHeader names written in the PGN tags that the game doesn't know
+type PGNHeader struct {
+	Event string
+	Site  string
+	White string
+	Black string
+}
+
+//ResultString returns the result as it is written in PGN
+func (g *Game) ResultString() string {
+	return g.Result.String()
+}
+
+//pgnEscape escapes a PGN tag value
+func pgnEscape(str string) string {
+	str = strings.Replace(str, "\\", "\\\\", -1)
+	return strings.Replace(str, "\"", "\\\"", -1)
+}
+
+//PGN returns the game in portable game notation
+func (g *Game) PGN(header PGNHeader) string {
+	var result strings.Builder
+
+	date := "????.??.??"
+	if !g.Created.IsZero() {
+		date = g.Created.UTC().Format("2006.01.02")
+	}
+
+	white, black := header.White, header.Black
+	if white == "" {
+		white = g.White.ID
+	}
+	if black == "" {
+		black = g.Black.ID
+	}
+
+	event, site := header.Event, header.Site
+	if event == "" {
+		event = "?"
+	}
+	if site == "" {
+		site = "?"
+	}
+
+	tags := [][2]string{
+		{"Event", event},
+		{"Site", site},
+		{"Date", date},
+		{"Round", "-"},
+		{"White", white},
+		{"Black", black},
+		{"Result", g.ResultString()},
+	}
+	if g.StartFEN != "" {
+		tags = append(tags, [2]string{"SetUp", "1"}, [2]string{"FEN", g.StartFEN})
+	}
+
+	for _, tag := range tags {
+		fmt.Fprintf(&result, "[%s \"%s\"]\n", tag[0], pgnEscape(tag[1]))
+	}
+	result.WriteString("\n")
+
+	tokens := strings.Fields(strings.Join(g.movePairs(), " "))
+	tokens = append(tokens, g.ResultString())
+
+	lineLen := 0
+	for i, token := range tokens {
+		if i != 0 && lineLen+1+len(token) > pgnLineWidth {
+			result.WriteString("\n")
+			lineLen = 0
+		} else if i != 0 {
+			result.WriteString(" ")
+			lineLen++
+		}
+		result.WriteString(token)
+		lineLen += len(token)
+	}
+	result.WriteString("\n")
+
+	return result.String()
+}
+
+var (
+	pgnTagRe      = regexp.MustCompile(`^\[(\w+)\s+"((?:[^"\\]|\\.)*)"\]$`)
+	pgnCommentRe  = regexp.MustCompile(`(?s)\{.*?\}|;[^\n]*`)
+	pgnMoveNumRe  = regexp.MustCompile(`^\d+\.+`)
+	pgnResultStrs = []string{"1-0", "0-1", "1/2-1/2", "*"}
+)
+
+//PGNGame a game read from a PGN
+type PGNGame struct {
+	Tags   map[string]string
+	Moves  []string
+	Result string
+}
+
+//ParsePGN reads the first game in a PGN
+func ParsePGN(pgn string) (*PGNGame, error) {
+	result := &PGNGame{
+		Tags:   make(map[string]string),
+		Result: "*",
+	}
+
+	var movetext strings.Builder
+	for _, line := range strings.Split(strings.Replace(pgn, "\r", "", -1), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			//Tags after the movetext belong to the next game
+			if len(result.Moves) > 0 || strings.TrimSpace(movetext.String()) != "" {
+				break
+			}
+
+			matches := pgnTagRe.FindStringSubmatch(line)
+			if matches == nil {
+				return nil, errors.Errorf("invalid PGN tag %s", line)
+			}
+			value := strings.Replace(matches[2], "\\\"", "\"", -1)
+			result.Tags[matches[1]] = strings.Replace(value, "\\\\", "\\", -1)
+			continue
+		}
+
+		movetext.WriteString(line)
+		movetext.WriteString("\n")
+	}
+
+	text := pgnCommentRe.ReplaceAllString(movetext.String(), " ")
+
+	depth := 0
+	for _, token := range strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ").Replace(text)) {
+		//Skip variations
+		switch token {
+		case "(":
+			depth++
+			continue
+		case ")":
+			depth--
+			continue
+		}
+		if depth > 0 || strings.HasPrefix(token, "$") {
+			continue
+		}
+
+		token = pgnMoveNumRe.ReplaceAllString(token, "")
+		if token == "" {
+			continue
+		}
+
+		isResult := false
+		for _, val := range pgnResultStrs {
+			if token == val {
+				result.Result = val
+				isResult = true
+			}
+		}
+		if isResult {
+			break
+		}
+
+		result.Moves = append(result.Moves, token)
+	}
+
+	if depth != 0 {
+		return nil, errors.New("PGN has unclosed variations")
+	}
+
+	if tag, ok := result.Tags["Result"]; ok && result.Result == "*" {
+		result.Result = tag
+	}
+
+	return result, nil
+}
+
+//CreateGameFromPGN creates a game between two players by playing every move
+//in the PGN checking each one is legal
+func CreateGameFromPGN(white, black, guildID string, whiteColor, blackColor color.RGBA, pgn string) (Game, error) {
+	parsed, err := ParsePGN(pgn)
+	if err != nil {
+		return Game{}, err
+	}
+
+	result := CreateGame(white, black, guildID, whiteColor, blackColor)
+	if fen, ok := parsed.Tags["FEN"]; ok {
+		start, err := FromFEN(fen)
+		if err != nil {
+			return Game{}, err
+		}
+		start.White, start.Black = result.White, result.Black
+		start.UID, start.GuildID, start.Created = result.UID, result.GuildID, result.Created
+		result = start
+	}
+
+	for _, san := range parsed.Moves {
+		moveNumber := fmt.Sprintf("%d.", result.fullMove)
+		if result.Turn == SideBlack {
+			moveNumber += ".."
+		}
+
+		mv, err := result.ParseSAN(san)
+		if err != nil {
+			return Game{}, errors.Wrapf(err, "move %s", moveNumber)
+		}
+
+		if err := result.ValidMove(result.GetSidePlayer(result.Turn).ID, mv); err != nil {
+			return Game{}, errors.Wrapf(err, "move %s %s", moveNumber, san)
+		}
+		result.MakeMove(mv)
+	}
+
+	//Games that ended by resignation or agreement keep their result
+	if !result.Over() {
+		switch parsed.Result {
+		case "1-0":
+			result.Result = WinFor(SideWhite, ReasonNone)
+		case "0-1":
+			result.Result = WinFor(SideBlack, ReasonNone)
+		case "1/2-1/2":
+			result.Result = DrawBy(ReasonNone)
+		}
+	}
+
+	return result, nil
+}
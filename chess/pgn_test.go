@@ -0,0 +1,218 @@
+package chess
+
+import (
+	"image/color"
+	"strings"
+	"testing"
+	"time"
+)
+
+//operaGame Morphy's opera game in SAN
+const operaGame = "e4 e5 Nf3 d6 d4 Bg4 dxe5 Bxf3 Qxf3 dxe5 Bc4 Nf6 Qb3 Qe7 Nc3 c6 Bg5 b5 " +
+	"Nxb5 cxb5 Bxb5+ Nbd7 O-O-O Rd8 Rxd7 Rxd7 Rd1 Qe6 Bxd7+ Nxd7 Qb8+ Nxb8 Rd8#"
+
+func TestPGNTags(t *testing.T) {
+	game := newTestGame()
+	game.Created = time.Date(2021, 2, 3, 23, 0, 0, 0, time.UTC)
+	playSAN(t, &game, "e4 e5 Nf3 Nc6 Bb5 a6")
+	game.Resign("white")
+
+	got := game.PGN(PGNHeader{Event: "Discord", Site: "guild", White: `Al "The Bot" \ x`})
+	want := "[Event \"Discord\"]\n" +
+		"[Site \"guild\"]\n" +
+		"[Date \"2021.02.03\"]\n" +
+		"[Round \"-\"]\n" +
+		"[White \"Al \\\"The Bot\\\" \\\\ x\"]\n" +
+		"[Black \"black\"]\n" +
+		"[Result \"0-1\"]\n" +
+		"\n" +
+		"1. e4 e5 2. Nf3 Nc6 3. Bb5 a6 0-1\n"
+
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestPGNUnknownTags(t *testing.T) {
+	game := newTestGame()
+	game.Created = time.Time{}
+
+	got := game.PGN(PGNHeader{})
+	for _, tag := range []string{`[Event "?"]`, `[Site "?"]`, `[Date "????.??.??"]`, `[White "white"]`, `[Black "black"]`} {
+		if !strings.Contains(got, tag+"\n") {
+			t.Errorf("missing %s in\n%s", tag, got)
+		}
+	}
+}
+
+func TestPGNResult(t *testing.T) {
+	tests := []struct {
+		result Result
+		want   string
+	}{
+		{Result{}, "*"},
+		{WinFor(SideWhite, ReasonCheckmate), "1-0"},
+		{WinFor(SideBlack, ReasonTimeout), "0-1"},
+		{DrawBy(ReasonAgreement), "1/2-1/2"},
+		{AbortedBy(ReasonInactivity), "*"},
+	}
+
+	for _, test := range tests {
+		game := newTestGame()
+		playSAN(t, &game, "d4")
+		game.Result = test.result
+
+		pgn := game.PGN(PGNHeader{})
+		if !strings.Contains(pgn, "[Result \""+test.want+"\"]") || !strings.HasSuffix(pgn, "1. d4 "+test.want+"\n") {
+			t.Errorf("%v result written as\n%s", test.result, pgn)
+		}
+	}
+}
+
+func TestPGNSetUp(t *testing.T) {
+	fen := "8/8/8/8/8/8/4k2P/K7 b - - 0 30"
+	game, err := FromFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	playSAN(t, &game, "Kd3 Ka2")
+
+	pgn := game.PGN(PGNHeader{})
+	want := "[Result \"*\"]\n[SetUp \"1\"]\n[FEN \"" + fen + "\"]\n\n30... Kd3 31. Ka2 *\n"
+	if !strings.HasSuffix(pgn, want) {
+		t.Errorf("got\n%s\nwant it to end with\n%s", pgn, want)
+	}
+}
+
+func TestPGNLineWrapping(t *testing.T) {
+	game := newTestGame()
+	playSAN(t, &game, operaGame)
+
+	pgn := game.PGN(PGNHeader{})
+	movetext := pgn[strings.Index(pgn, "\n\n")+2:]
+	lines := strings.Split(strings.TrimSuffix(movetext, "\n"), "\n")
+
+	if len(lines) < 2 {
+		t.Fatalf("movetext wasn't wrapped\n%s", movetext)
+	}
+	for _, line := range lines {
+		if len(line) > pgnLineWidth || strings.HasPrefix(line, " ") || strings.HasSuffix(line, " ") {
+			t.Errorf("bad line %q", line)
+		}
+	}
+
+	want := "1. e4 e5 2. Nf3 d6 3. d4 Bg4 4. dxe5 Bxf3 5. Qxf3 dxe5 6. Bc4 Nf6 7. Qb3 Qe7 " +
+		"8. Nc3 c6 9. Bg5 b5 10. Nxb5 cxb5 11. Bxb5+ Nbd7 12. O-O-O Rd8 13. Rxd7 Rxd7 " +
+		"14. Rd1 Qe6 15. Bxd7+ Nxd7 16. Qb8+ Nxb8 17. Rd8# 1-0"
+	if got := strings.Join(lines, " "); got != want {
+		t.Errorf("got %s\nwant %s", got, want)
+	}
+}
+
+func TestParsePGN(t *testing.T) {
+	pgn := `[Event "Casual \"blitz\""]
+[White "a\\b"]
+[Result "1-0"]
+
+1. e4 {best by test} e5 2. Nf3 (2. f4 exf4 (2... d5) 3. Nf3) Nc6 ; a comment
+3. Bb5 $1 a6 1-0
+
+[Event "Next"]
+
+1. d4 *`
+
+	game, err := ParsePGN(pgn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if game.Tags["Event"] != `Casual "blitz"` || game.Tags["White"] != `a\b` || len(game.Tags) != 3 {
+		t.Errorf("bad tags %v", game.Tags)
+	}
+	if got := strings.Join(game.Moves, " "); got != "e4 e5 Nf3 Nc6 Bb5 a6" {
+		t.Errorf("got moves %s", got)
+	}
+	if game.Result != "1-0" {
+		t.Errorf("got result %s", game.Result)
+	}
+}
+
+func TestParsePGNErrors(t *testing.T) {
+	for _, pgn := range []string{
+		"[Event Casual]\n\n1. e4",
+		"1. e4 (1. d4 d5",
+	} {
+		if _, err := ParsePGN(pgn); err == nil {
+			t.Errorf("%q: expected an error", pgn)
+		}
+	}
+}
+
+func TestCreateGameFromPGN(t *testing.T) {
+	game, err := CreateGameFromPGN(
+		"1", "2", "guild", color.RGBA{}, color.RGBA{}, "1. e4 e5 2. Nf3 {develops} (2. Bc4) Nc6 *",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3"
+	if game.FEN() != want || game.White.ID != "1" || game.Black.ID != "2" || game.Over() {
+		t.Errorf("got %s white %s black %s over %v", game.FEN(), game.White.ID, game.Black.ID, game.Over())
+	}
+
+	game, err = CreateGameFromPGN("1", "2", "guild", color.RGBA{}, color.RGBA{}, operaGame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.Result != WinFor(SideWhite, ReasonCheckmate) {
+		t.Errorf("got result %v", game.Result)
+	}
+
+	game, err = CreateGameFromPGN("1", "2", "guild", color.RGBA{}, color.RGBA{}, "1. d4 d5 0-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.Result.Winner() != SideBlack {
+		t.Errorf("result tag ignored got %v", game.Result)
+	}
+}
+
+func TestCreateGameFromPGNIllegalMove(t *testing.T) {
+	for _, pgn := range []string{
+		"1. e4 e5 2. Ke3",
+		"1. e4 e5 2. Nf6",
+		"1. e4 e5 2. Qh5 Nc6 3. Bc4 Nf6 4. Qxf7# Ke7",
+	} {
+		if _, err := CreateGameFromPGN("1", "2", "guild", color.RGBA{}, color.RGBA{}, pgn); err == nil {
+			t.Errorf("%q: expected an error", pgn)
+		}
+	}
+}
+
+func TestCreateGameFromPGNSetUp(t *testing.T) {
+	pgn := `[SetUp "1"]
+[FEN "4k3/8/8/8/8/8/4P3/4K3 b - - 0 40"]
+
+40... Kd7 41. e4 *`
+
+	game, err := CreateGameFromPGN("1", "2", "guild", color.RGBA{}, color.RGBA{}, pgn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "8/3k4/8/8/4P3/8/8/4K3 b - e3 0 41"; game.FEN() != want {
+		t.Errorf("got %s want %s", game.FEN(), want)
+	}
+	if game.UID == "" || game.White.ID != "1" || game.GuildID != "guild" {
+		t.Errorf("players not kept white %s guild %s", game.White.ID, game.GuildID)
+	}
+
+	//The side not to move is in check so its king could be taken
+	pgn = `[FEN "4k2R/8/8/8/8/8/8/4K3 w - - 0 1"]
+
+1. Rxe8 *`
+	if _, err := CreateGameFromPGN("1", "2", "guild", color.RGBA{}, color.RGBA{}, pgn); err == nil {
+		t.Error("expected an impossible start position to be rejected")
+	}
+}
@@ -0,0 +1,229 @@
+package chess
+
+//Outcome how a game finished
+type Outcome int
+
+const (
+	//OutcomeOngoing the game is still being played
+	OutcomeOngoing Outcome = iota
+	//OutcomeWhiteWins white won
+	OutcomeWhiteWins
+	//OutcomeBlackWins black won
+	OutcomeBlackWins
+	//OutcomeDraw nobody won
+	OutcomeDraw
+	//OutcomeAborted the game was stopped without a result
+	OutcomeAborted
+)
+
+//EndReason why a game finished
+type EndReason int
+
+const (
+	//ReasonNone the game hasn't finished or the reason isn't known
+	ReasonNone EndReason = iota
+	//ReasonCheckmate ReasonCheckmate
+	ReasonCheckmate
+	//ReasonResignation ReasonResignation
+	ReasonResignation
+	//ReasonStalemate ReasonStalemate
+	ReasonStalemate
+	//ReasonFiftyMoves fifty moves by each side without a capture or pawn move
+	ReasonFiftyMoves
+	//ReasonThreefoldRepetition the same position happened three times
+	ReasonThreefoldRepetition
+	//ReasonInsufficientMaterial neither side has enough pieces left to checkmate
+	ReasonInsufficientMaterial
+	//ReasonAgreement both players agreed to a draw
+	ReasonAgreement
+	//ReasonTimeout the player ran out of time
+	ReasonTimeout
+	//ReasonTimeoutVsInsufficientMaterial the player ran out of time but
+	//their opponent couldn't checkmate
+	ReasonTimeoutVsInsufficientMaterial
+	//ReasonInactivity nobody moved for too long
+	ReasonInactivity
+)
+
+func (r EndReason) String() string {
+	switch r {
+	case ReasonCheckmate:
+		return "checkmate"
+	case ReasonResignation:
+		return "resignation"
+	case ReasonStalemate:
+		return "stalemate"
+	case ReasonFiftyMoves:
+		return "the fifty move rule"
+	case ReasonThreefoldRepetition:
+		return "threefold repetition"
+	case ReasonInsufficientMaterial:
+		return "insufficient material"
+	case ReasonAgreement:
+		return "agreement"
+	case ReasonTimeout:
+		return "timeout"
+	case ReasonTimeoutVsInsufficientMaterial:
+		return "timeout vs insufficient material"
+	case ReasonInactivity:
+		return "inactivity"
+	}
+
+	return "unknown"
+}
+
+//Result the result of a game and why
+type Result struct {
+	Outcome Outcome   `json:"outcome"`
+	Reason  EndReason `json:"reason"`
+}
+
+//WinFor returns a result where the side won
+func WinFor(side SideType, reason EndReason) Result {
+	if side == SideWhite {
+		return Result{OutcomeWhiteWins, reason}
+	}
+
+	return Result{OutcomeBlackWins, reason}
+}
+
+//DrawBy returns a drawn result
+func DrawBy(reason EndReason) Result {
+	return Result{OutcomeDraw, reason}
+}
+
+//AbortedBy returns a result for a game stopped without a winner
+func AbortedBy(reason EndReason) Result {
+	return Result{OutcomeAborted, reason}
+}
+
+//Winner returns the side that won or SideEmpty if nobody did
+func (r Result) Winner() SideType {
+	switch r.Outcome {
+	case OutcomeWhiteWins:
+		return SideWhite
+	case OutcomeBlackWins:
+		return SideBlack
+	}
+
+	return SideEmpty
+}
+
+//String returns the result as it is written in PGN
+func (r Result) String() string {
+	switch r.Outcome {
+	case OutcomeWhiteWins:
+		return "1-0"
+	case OutcomeBlackWins:
+		return "0-1"
+	case OutcomeDraw:
+		return "1/2-1/2"
+	}
+
+	return "*"
+}
+
+//Over returns true once the game has a result
+func (g *Game) Over() bool {
+	return g.Result.Outcome != OutcomeOngoing
+}
+
+//Resign ends the game with the player's opponent winning
+func (g *Game) Resign(id string) error {
+	if g.Over() {
+		return ErrGameOver
+	}
+
+	if g.White.ID != id && g.Black.ID != id {
+		return ErrNotAPlayer
+	}
+
+	g.Result = WinFor(g.GetPlayer(id).Side.other(), ReasonResignation)
+	return nil
+}
+
+//insufficientMaterial returns true if neither side can ever checkmate
+func (g *Game) insufficientMaterial() bool {
+	var minors []Postion
+	for r := 0; r < rowHight; r++ {
+		for c := 0; c < rowWidth; c++ {
+			switch g.board[r][c].Kind {
+			case PieceTypePawn, PieceTypeRook, PieceTypeQueen:
+				return false
+			case PieceTypeKnight, PieceTypeBishop:
+				minors = append(minors, Postion{r, c})
+			}
+		}
+	}
+
+	//A king with a single bishop or knight against a king
+	if len(minors) <= 1 {
+		return true
+	}
+
+	//Only bishops all on the same colour cells
+	for _, val := range minors {
+		if g.getAt(val).Kind != PieceTypeBishop ||
+			(val.Row+val.Col)%2 != (minors[0].Row+minors[0].Col)%2 {
+			return false
+		}
+	}
+
+	return true
+}
+
+//enPassantPossible returns true if a pawn of the side could take en passant
+func (g *Game) enPassantPossible(side SideType) bool {
+	if !g.canEnPassant {
+		return false
+	}
+
+	forward := -1
+	if side == SideBlack {
+		forward = 1
+	}
+
+	row := g.enPassant.Row - forward
+	for _, col := range []int{g.enPassant.Col - 1, g.enPassant.Col + 1} {
+		if col < 0 || col >= rowWidth {
+			continue
+		}
+		if piece := g.board[row][col]; piece.Kind == PieceTypePawn && piece.Side == side {
+			return true
+		}
+	}
+
+	return false
+}
+
+//positionKey identifies a position for repetition
+func (g *Game) positionKey(turn SideType) string {
+	return g.fenPosition(turn, g.enPassantPossible(turn))
+}
+
+//recordPosition counts the current position for threefold repetition
+func (g *Game) recordPosition(turn SideType) {
+	if g.repetitions == nil {
+		g.repetitions = make(map[string]int)
+	}
+
+	g.repetitions[g.positionKey(turn)]++
+}
+
+//updateResult ends the game if it has been won or drawn by the last move
+func (g *Game) updateResult() {
+	hasMove := g.hasLegalMove()
+
+	switch {
+	case !hasMove && g.InCheck():
+		g.Result = WinFor(g.Turn.other(), ReasonCheckmate)
+	case !hasMove:
+		g.Result = DrawBy(ReasonStalemate)
+	case g.insufficientMaterial():
+		g.Result = DrawBy(ReasonInsufficientMaterial)
+	case g.halfMove >= 100:
+		g.Result = DrawBy(ReasonFiftyMoves)
+	case g.repetitions[g.positionKey(g.Turn)] >= 3:
+		g.Result = DrawBy(ReasonThreefoldRepetition)
+	}
+}
@@ -0,0 +1,56 @@
+package chess
+
+import (
+	"strings"
+	"testing"
+)
+
+func playSAN(t *testing.T, game *Game, score string) {
+	t.Helper()
+
+	for _, san := range strings.Fields(score) {
+		mv, err := game.ParseSAN(san)
+		if err != nil {
+			t.Fatalf("parsing %s: %v", san, err)
+		}
+		game.MakeMove(mv)
+	}
+}
+
+func TestDrawRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		fen   string
+		score string
+		want  Result
+	}{
+		{"threefold repetition", StartingFEN, "Nf3 Nf6 Ng1 Ng8 Nf3 Nf6 Ng1 Ng8", DrawBy(ReasonThreefoldRepetition)},
+		{"insufficient material", "4k3/8/8/8/8/8/3p4/4K2B w - - 0 1", "Kxd2", DrawBy(ReasonInsufficientMaterial)},
+		{"fifty moves", "4k3/8/8/8/8/8/8/R3K3 w - - 99 80", "Ra2", DrawBy(ReasonFiftyMoves)},
+		{"stalemate", "k7/8/1Q6/8/8/8/8/4K3 w - - 0 1", "Qc7", DrawBy(ReasonStalemate)},
+		{"checkmate", "k7/8/1Q6/8/8/8/8/1R2K3 w - - 0 1", "Qb7", WinFor(SideWhite, ReasonCheckmate)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			game, err := FromFEN(test.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			playSAN(t, &game, test.score)
+			if game.Result != test.want {
+				t.Errorf("got %+v want %+v", game.Result, test.want)
+			}
+		})
+	}
+}
+
+func TestNoDrawBeforeThirdRepetition(t *testing.T) {
+	game := newTestGame()
+	playSAN(t, &game, "Nf3 Nf6 Ng1 Ng8 Nf3 Nf6 Ng1")
+
+	if game.Over() {
+		t.Errorf("game ended early with %+v", game.Result)
+	}
+}
@@ -0,0 +1,279 @@
+package chess
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+//movePairsPerLine how many numbered move pairs are put on a line
+const movePairsPerLine = 4
+
+//disambiguation returns what is needed to tell the moving piece apart from
+//other pieces of the same type that can reach the same cell
+func (g *Game) disambiguation(mv Move) string {
+	piece := g.getAt(mv.From)
+	sameCol, sameRow, others := false, false, false
+
+	for _, val := range g.findPieces(piece.Side, piece.Kind) {
+		if val == mv.From {
+			continue
+		}
+
+		other := Move{From: val, To: mv.To, Promotion: mv.Promotion}
+		if g.legalMove(other) != nil {
+			continue
+		}
+
+		others = true
+		if val.Col == mv.From.Col {
+			sameCol = true
+		}
+		if val.Row == mv.From.Row {
+			sameRow = true
+		}
+	}
+
+	switch {
+	case !others:
+		return ""
+	case !sameCol:
+		return colStr(mv.From.Col)
+	case !sameRow:
+		return rankStr(mv.From.Row)
+	}
+
+	return colStr(mv.From.Col) + rankStr(mv.From.Row)
+}
+
+//MoveSAN returns the move in standard algebraic notation it must be called
+//before the move is made
+func (g *Game) MoveSAN(mv Move) string {
+	var result strings.Builder
+
+	piece := g.getAt(mv.From)
+	capture := g.getAt(mv.To).Kind != PieceTypeEmpty ||
+		(piece.Kind == PieceTypePawn && mv.From.Col != mv.To.Col)
+
+	switch {
+	case piece.Kind == PieceTypeKing && isCastling(mv) && mv.To.Col > mv.From.Col:
+		result.WriteString("O-O")
+	case piece.Kind == PieceTypeKing && isCastling(mv):
+		result.WriteString("O-O-O")
+	default:
+		result.WriteString(piece.Kind.NotationStr())
+		if piece.Kind == PieceTypePawn && capture {
+			result.WriteString(colStr(mv.From.Col))
+		} else if piece.Kind != PieceTypePawn {
+			result.WriteString(g.disambiguation(mv))
+		}
+		if capture {
+			result.WriteString("x")
+		}
+		result.WriteString(strings.ToLower(mv.To.String()))
+		if mv.Promotion != PieceTypeEmpty {
+			result.WriteString("=" + mv.Promotion.NotationStr())
+		}
+	}
+
+	//Try the move to see if it checks or mates
+	prev, turn := g.position, g.Turn
+	defer func() {
+		g.position, g.Turn = prev, turn
+	}()
+	g.processMove(mv)
+	g.Turn = piece.Side.other()
+
+	if g.InCheck() {
+		if g.hasLegalMove() {
+			result.WriteString("+")
+		} else {
+			result.WriteString("#")
+		}
+	}
+
+	return result.String()
+}
+
+//SANMoves returns every move made in standard algebraic notation
+func (g *Game) SANMoves() []string {
+	result := make([]string, 0, len(g.Moves))
+
+	g.position = g.initialPosition()
+	for _, mv := range g.Moves {
+		result = append(result, g.MoveSAN(mv))
+		g.processMove(mv)
+	}
+
+	return result
+}
+
+//movePairs returns the moves numbered with white and black's move together
+func (g *Game) movePairs() []string {
+	var result []string
+
+	moveNumber := g.initialPosition().fullMove
+	blackToMove := g.initialTurn() == SideBlack
+
+	for i, san := range g.SANMoves() {
+		switch {
+		case !blackToMove:
+			result = append(result, fmt.Sprintf("%d. %s", moveNumber, san))
+		case i == 0:
+			result = append(result, fmt.Sprintf("%d... %s", moveNumber, san))
+		default:
+			result[len(result)-1] += " " + san
+		}
+
+		if blackToMove {
+			moveNumber++
+		}
+		blackToMove = !blackToMove
+	}
+
+	return result
+}
+
+//sanRe matches a piece move in standard algebraic notation
+var sanRe = regexp.MustCompile("^([NBRQK])?([a-h])?([1-8])?(x)?([a-h][1-8])(?:=?([NBRQ]))?$")
+
+//ParseSAN finds the legal move described by the standard algebraic notation
+func (g *Game) ParseSAN(san string) (Move, error) {
+	str := strings.TrimRight(strings.TrimSpace(san), "+#!?")
+
+	switch str {
+	case "O-O", "0-0":
+		return g.matchSAN(san, func(mv Move) bool {
+			return g.getAt(mv.From).Kind == PieceTypeKing && isCastling(mv) && mv.To.Col > mv.From.Col
+		})
+	case "O-O-O", "0-0-0":
+		return g.matchSAN(san, func(mv Move) bool {
+			return g.getAt(mv.From).Kind == PieceTypeKing && isCastling(mv) && mv.To.Col < mv.From.Col
+		})
+	}
+
+	matches := sanRe.FindStringSubmatch(str)
+	if matches == nil {
+		return Move{}, errors.Errorf("%s is not a valid move", san)
+	}
+
+	kind := PieceTypePawn
+	if matches[1] != "" {
+		kind, _ = ParsePieceType(matches[1])
+	}
+	to := StringToPostion(matches[5])
+	promotion := PieceTypeEmpty
+	if matches[6] != "" {
+		promotion, _ = ParsePieceType(matches[6])
+	}
+	if kind == PieceTypePawn && promotion == PieceTypeEmpty && (to.Row == 0 || to.Row == rowHight-1) {
+		return Move{}, errors.Errorf("%s needs a promotion like %s=Q", san, matches[5])
+	}
+
+	return g.matchSAN(san, func(mv Move) bool {
+		if g.getAt(mv.From).Kind != kind || mv.To != to || mv.Promotion != promotion {
+			return false
+		}
+		if matches[2] != "" && colStr(mv.From.Col) != matches[2] {
+			return false
+		}
+		if matches[3] != "" && rankStr(mv.From.Row) != matches[3] {
+			return false
+		}
+
+		return true
+	})
+}
+
+//matchSAN returns the only legal move that matches
+func (g *Game) matchSAN(san string, match func(mv Move) bool) (Move, error) {
+	var found []Move
+	for _, mv := range g.LegalMoves() {
+		if match(mv) {
+			found = append(found, mv)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return Move{}, errors.Errorf("%s is not a legal move", san)
+	case 1:
+		return found[0], nil
+	}
+
+	options := make([]string, len(found))
+	for i, mv := range found {
+		options[i] = g.MoveSAN(mv)
+	}
+
+	return Move{}, errors.Errorf(
+		"%s is ambiguous it could be %s", san, strings.Join(options, " or "),
+	)
+}
+
+var (
+	//coordinateRe matches a move written as two cells like e2 e4 or e7 e8=Q
+	coordinateRe = regexp.MustCompile("^([a-h][1-8])\\s+([a-h][1-8])(?:=?([qrbn]))?$")
+	//uciRe matches a move in UCI long algebraic notation like e2e4 or e7e8q
+	uciRe = regexp.MustCompile("^([a-h][1-8])([a-h][1-8])([qrbn])?$")
+)
+
+//UCI returns the move in UCI long algebraic notation
+func (m Move) UCI() string {
+	result := strings.ToLower(m.From.String() + m.To.String())
+	if m.Promotion != PieceTypeEmpty {
+		result += strings.ToLower(m.Promotion.NotationStr())
+	}
+
+	return result
+}
+
+//ParseMove reads a move written as two cells, in UCI or in SAN
+func (g *Game) ParseMove(str string) (Move, error) {
+	str = strings.TrimSpace(str)
+	if str == "" {
+		return Move{}, errors.New("no move given")
+	}
+	lower := strings.ToLower(str)
+
+	matches := coordinateRe.FindStringSubmatch(lower)
+	if matches == nil {
+		matches = uciRe.FindStringSubmatch(lower)
+	}
+	if matches != nil {
+		mv := Move{
+			From: StringToPostion(matches[1]),
+			To:   StringToPostion(matches[2]),
+		}
+		if matches[3] != "" {
+			mv.Promotion, _ = ParsePieceType(matches[3])
+		}
+
+		return mv, nil
+	}
+
+	if strings.HasPrefix(lower, "o-o") {
+		str = strings.ToUpper(str)
+	}
+
+	//The piece a pawn promotes to can be written in any case
+	if i := strings.LastIndexByte(str, '='); i >= 0 {
+		str = str[:i+1] + strings.ToUpper(str[i+1:])
+	}
+
+	mv, err := g.ParseSAN(str)
+	if err == nil {
+		return mv, nil
+	}
+
+	//People often don't capitalise the piece
+	if strings.IndexByte("nrqk", str[0]) >= 0 {
+		if mv, capErr := g.ParseSAN(strings.ToUpper(str[:1]) + str[1:]); capErr == nil {
+			return mv, nil
+		}
+	}
+
+	return Move{}, err
+}
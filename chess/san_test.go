@@ -0,0 +1,147 @@
+package chess
+
+import (
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestAlgebraicNotationKnownGames(t *testing.T) {
+	tests := []struct {
+		name  string
+		score string
+		want  string
+	}{
+		{
+			"opera game",
+			"1. e4 e5 2. Nf3 d6 3. d4 Bg4 4. dxe5 Bxf3 5. Qxf3 dxe5 6. Bc4 Nf6 " +
+				"7. Qb3 Qe7 8. Nc3 c6 9. Bg5 b5 10. Nxb5 cxb5 11. Bxb5+ Nbd7 " +
+				"12. O-O-O Rd8 13. Rxd7 Rxd7 14. Rd1 Qe6 15. Bxd7+ Nxd7 " +
+				"16. Qb8+ Nxb8 17. Rd8#",
+			"1. e4 e5 2. Nf3 d6 3. d4 Bg4 4. dxe5 Bxf3\n" +
+				"5. Qxf3 dxe5 6. Bc4 Nf6 7. Qb3 Qe7 8. Nc3 c6\n" +
+				"9. Bg5 b5 10. Nxb5 cxb5 11. Bxb5+ Nbd7 12. O-O-O Rd8\n" +
+				"13. Rxd7 Rxd7 14. Rd1 Qe6 15. Bxd7+ Nxd7 16. Qb8+ Nxb8\n" +
+				"17. Rd8#",
+		},
+		{
+			"lasker trap",
+			"1. d4 d5 2. c4 e5 3. dxe5 d4 4. e3 Bb4+ 5. Bd2 dxe3 6. Bxb4 exf2+ " +
+				"7. Ke2 fxg1=N+ 8. Ke1 Qh4+ 9. Kd2 Nc6 10. Bc3 Bg4",
+			"1. d4 d5 2. c4 e5 3. dxe5 d4 4. e3 Bb4+\n" +
+				"5. Bd2 dxe3 6. Bxb4 exf2+ 7. Ke2 fxg1=N+ 8. Ke1 Qh4+\n" +
+				"9. Kd2 Nc6 10. Bc3 Bg4",
+		},
+		{
+			"en passant",
+			"1. e4 Nf6 2. e5 d5 3. exd6 cxd6 4. Nf3 Nc6 5. Be2 g6 6. O-O Bg7",
+			"1. e4 Nf6 2. e5 d5 3. exd6 cxd6 4. Nf3 Nc6\n" +
+				"5. Be2 g6 6. O-O Bg7",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			game, err := CreateGameFromPGN(
+				"white", "black", "guild",
+				color.RGBA{255, 255, 255, 255}, color.RGBA{0, 0, 0, 255}, test.score,
+			)
+			if err != nil {
+				t.Fatalf("loading score: %v", err)
+			}
+
+			if got := game.AlgebraicNotation(); got != test.want {
+				t.Errorf("got\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMoveSANDisambiguation(t *testing.T) {
+	tests := []struct {
+		fen  string
+		mv   Move
+		want string
+	}{
+		{"4k3/8/8/8/8/8/8/R4RK1 w - - 0 1", testMove("a1", "d1"), "Rad1"},
+		{"4k3/R7/8/8/8/8/8/R3K3 w - - 0 1", testMove("a1", "a4"), "R1a4"},
+		{"4k3/8/8/8/8/8/8/R3K3 w Q - 0 1", testMove("a1", "d1"), "Rd1"},
+		{"1k6/8/8/8/4Q2Q/8/K7/7Q w - - 0 1", testMove("h4", "e1"), "Qh4e1"},
+		{"4k3/6P1/8/8/8/8/8/4K3 w - - 0 1", Move{StringToPostion("g7"), StringToPostion("g8"), PieceTypeQueen}, "g8=Q+"},
+	}
+
+	for _, test := range tests {
+		game, err := FromFEN(test.fen)
+		if err != nil {
+			t.Fatalf("%s: %v", test.fen, err)
+		}
+
+		if got := game.MoveSAN(test.mv); got != test.want {
+			t.Errorf("%s: got %s want %s", test.fen, got, test.want)
+		}
+	}
+}
+
+func TestParseSANRoundTrip(t *testing.T) {
+	game := newTestGame()
+	score := "e4 e5 Nf3 Nc6 Bb5 a6 Ba4 Nf6 O-O Be7"
+
+	for _, san := range strings.Fields(score) {
+		mv, err := game.ParseSAN(san)
+		if err != nil {
+			t.Fatalf("parsing %s: %v", san, err)
+		}
+		game.MakeMove(mv)
+	}
+
+	if got := strings.Join(game.SANMoves(), " "); got != score {
+		t.Errorf("got %s want %s", got, score)
+	}
+}
+
+func TestParseMove(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Move
+	}{
+		{"e2 e4", testMove("e2", "e4")},
+		{"E2 E4", testMove("e2", "e4")},
+		{"e2e4", testMove("e2", "e4")},
+		{"e4", testMove("e2", "e4")},
+		{"Nf3", testMove("g1", "f3")},
+		{"nf3", testMove("g1", "f3")},
+	}
+
+	for _, test := range tests {
+		game := newTestGame()
+		got, err := game.ParseMove(test.input)
+		if err != nil {
+			t.Errorf("%s: %v", test.input, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s: got %v want %v", test.input, got, test.want)
+		}
+	}
+
+	promote := testMove("e7", "e8")
+	promote.Promotion = PieceTypeQueen
+	for _, input := range []string{"e8=Q", "e8=q", "e8Q", "e7 e8=q", "e7e8q"} {
+		game, err := FromFEN("8/4P3/8/8/8/8/k7/4K3 w - - 0 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := game.ParseMove(input)
+		if err != nil {
+			t.Errorf("%s: %v", input, err)
+		} else if got != promote {
+			t.Errorf("%s: got %v want %v", input, got, promote)
+		}
+	}
+
+	game := newTestGame()
+	if _, err := game.ParseMove("Nf4"); err == nil {
+		t.Error("illegal SAN was accepted")
+	}
+}
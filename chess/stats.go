@@ -0,0 +1,87 @@
+package chess
+
+import (
+	"sort"
+)
+
+//PlayerStats a player's record over their finished games
+type PlayerStats struct {
+	PlayerID string
+	Wins     int
+	Losses   int
+	Draws    int
+	//Moves the full moves played over every game
+	Moves    int
+	Openings map[string]int
+}
+
+//OpeningCount how many games a player has played an opening in
+type OpeningCount struct {
+	Name  string
+	Games int
+}
+
+//NewPlayerStats creates empty stats for the player
+func NewPlayerStats(playerID string) *PlayerStats {
+	return &PlayerStats{
+		PlayerID: playerID,
+		Openings: make(map[string]int),
+	}
+}
+
+//Add counts a finished game, games the player isn't in or that were
+//aborted are skipped
+func (s *PlayerStats) Add(g *Game) {
+	if g.White.ID != s.PlayerID && g.Black.ID != s.PlayerID {
+		return
+	}
+
+	switch {
+	case !g.Over() || g.Result.Outcome == OutcomeAborted:
+		return
+	case g.Result.Outcome == OutcomeDraw:
+		s.Draws++
+	case g.GetSidePlayer(g.Result.Winner()).ID == s.PlayerID:
+		s.Wins++
+	default:
+		s.Losses++
+	}
+
+	s.Moves += (len(g.Moves) + 1) / 2
+	s.Openings[g.Opening()]++
+}
+
+//Games the number of games counted
+func (s *PlayerStats) Games() int {
+	return s.Wins + s.Losses + s.Draws
+}
+
+//AverageLength the average number of full moves in a game
+func (s *PlayerStats) AverageLength() float64 {
+	if s.Games() == 0 {
+		return 0
+	}
+
+	return float64(s.Moves) / float64(s.Games())
+}
+
+//FavouriteOpenings the count most played openings, most played first
+func (s *PlayerStats) FavouriteOpenings(count int) []OpeningCount {
+	result := make([]OpeningCount, 0, len(s.Openings))
+	for name, games := range s.Openings {
+		result = append(result, OpeningCount{Name: name, Games: games})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Games != result[j].Games {
+			return result[i].Games > result[j].Games
+		}
+		return result[i].Name < result[j].Name
+	})
+
+	if len(result) > count {
+		result = result[:count]
+	}
+
+	return result
+}
@@ -0,0 +1,78 @@
+package chess
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOpening(t *testing.T) {
+	tests := []struct {
+		score string
+		want  string
+	}{
+		{"", OpeningUnknown},
+		{"e4", "King's Pawn Opening"},
+		{"e4 e5 Nf3 Nc6 Bb5 a6", "Ruy Lopez"},
+		{"e4 e5 Nf3 Nc6 Bc4 Nf6", "Two Knights Defence"},
+		{"e4 c5 Nf3 d6 d4 cxd4 Nxd4 Nf6 Nc3 a6 Be3", "Sicilian Defence, Najdorf Variation"},
+		{"d4 Nf6 c4 e6 Nc3 Bb4", "Nimzo-Indian Defence"},
+		{"a3 e5", OpeningUnknown},
+	}
+
+	for _, test := range tests {
+		game := newTestGame()
+		playSAN(t, &game, test.score)
+
+		if got := game.Opening(); got != test.want {
+			t.Errorf("%q opening %q want %q", test.score, got, test.want)
+		}
+	}
+
+	game, err := FromFEN("4k3/8/8/8/8/8/8/4K2R w K - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := game.Opening(); got != OpeningCustom {
+		t.Errorf("custom position opening %q", got)
+	}
+}
+
+func TestPlayerStats(t *testing.T) {
+	finished := func(white, black, score string, result Result) *Game {
+		game := newTestGame()
+		game.White.ID, game.Black.ID = white, black
+		playSAN(t, &game, score)
+		game.Result = result
+		return &game
+	}
+
+	stats := NewPlayerStats("me")
+	for _, game := range []*Game{
+		finished("me", "you", "e4 e5 Qh5 Nc6 Bc4 Nf6 Qxf7#", WinFor(SideWhite, ReasonCheckmate)),
+		finished("you", "me", "e4 c5", WinFor(SideWhite, ReasonResignation)),
+		finished("me", "you", "e4 e5 Nf3", DrawBy(ReasonAgreement)),
+		finished("you", "me", "d4", WinFor(SideBlack, ReasonTimeout)),
+		finished("me", "you", "d4", AbortedBy(ReasonInactivity)),
+		finished("them", "you", "c4", WinFor(SideWhite, ReasonResignation)),
+	} {
+		stats.Add(game)
+	}
+
+	if stats.Wins != 2 || stats.Losses != 1 || stats.Draws != 1 || stats.Games() != 4 {
+		t.Errorf("record %d/%d/%d", stats.Wins, stats.Losses, stats.Draws)
+	}
+
+	//4 + 1 + 2 + 1 full moves
+	if stats.AverageLength() != 2 {
+		t.Errorf("average length %f", stats.AverageLength())
+	}
+
+	want := []OpeningCount{{"King's Pawn Game", 2}, {"Queen's Pawn Opening", 1}}
+	if got := stats.FavouriteOpenings(2); !reflect.DeepEqual(got, want) {
+		t.Errorf("favourite openings %v want %v", got, want)
+	}
+
+	if NewPlayerStats("nobody").AverageLength() != 0 {
+		t.Error("no games should average 0 moves")
+	}
+}
@@ -0,0 +1,85 @@
+package chess
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+//TimeControl how much time each player gets
+type TimeControl struct {
+	//Base the time each player starts with
+	Base time.Duration `json:"base"`
+	//Increment the time added after each move
+	Increment time.Duration `json:"increment"`
+	//PerMove the time allowed for each move in correspondence games
+	PerMove time.Duration `json:"per_move"`
+}
+
+var (
+	liveTimeRe           = regexp.MustCompile(`^(\d+)\+(\d+)$`)
+	correspondenceTimeRe = regexp.MustCompile(`^(\d+) ?d(?:ays?)?$`)
+)
+
+//ParseTimeControl reads a time control written as minutes+increment (5+3)
+//or days per move (3d) an empty string or none is an untimed game
+func ParseTimeControl(str string) (TimeControl, error) {
+	str = strings.ToLower(strings.TrimSpace(str))
+
+	if str == "" || str == "none" || str == "untimed" {
+		return TimeControl{}, nil
+	}
+
+	if matches := liveTimeRe.FindStringSubmatch(str); matches != nil {
+		minutes, _ := strconv.Atoi(matches[1])
+		seconds, _ := strconv.Atoi(matches[2])
+		if minutes == 0 {
+			return TimeControl{}, errors.Errorf("time control %s has no time on the clock", str)
+		}
+
+		return TimeControl{
+			Base:      time.Duration(minutes) * time.Minute,
+			Increment: time.Duration(seconds) * time.Second,
+		}, nil
+	}
+
+	if matches := correspondenceTimeRe.FindStringSubmatch(str); matches != nil {
+		days, _ := strconv.Atoi(matches[1])
+		if days == 0 {
+			return TimeControl{}, errors.Errorf("time control %s has no time for a move", str)
+		}
+
+		return TimeControl{PerMove: time.Duration(days) * 24 * time.Hour}, nil
+	}
+
+	return TimeControl{}, errors.Errorf("unknown time control %s try 5+3 or 3d", str)
+}
+
+//Timed returns true if the time control limits the players time
+func (t TimeControl) Timed() bool {
+	return t.Base > 0 || t.PerMove > 0
+}
+
+//Correspondence returns true if players get a set time for each move
+func (t TimeControl) Correspondence() bool {
+	return t.PerMove > 0
+}
+
+func (t TimeControl) String() string {
+	switch {
+	case t.Correspondence():
+		days := int(t.PerMove / (24 * time.Hour))
+		if days == 1 {
+			return "1 day per move"
+		}
+		return fmt.Sprintf("%d days per move", days)
+	case t.Timed():
+		return fmt.Sprintf("%d+%d", int(t.Base/time.Minute), int(t.Increment/time.Second))
+	}
+
+	return "untimed"
+}
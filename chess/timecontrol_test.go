@@ -0,0 +1,38 @@
+package chess
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeControl(t *testing.T) {
+	tests := []struct {
+		str  string
+		want TimeControl
+		name string
+	}{
+		{"5+3", TimeControl{Base: 5 * time.Minute, Increment: 3 * time.Second}, "5+3"},
+		{"10+0", TimeControl{Base: 10 * time.Minute}, "10+0"},
+		{"3d", TimeControl{PerMove: 72 * time.Hour}, "3 days per move"},
+		{"1 day", TimeControl{PerMove: 24 * time.Hour}, "1 day per move"},
+		{"", TimeControl{}, "untimed"},
+		{"None", TimeControl{}, "untimed"},
+	}
+
+	for _, test := range tests {
+		got, err := ParseTimeControl(test.str)
+		if err != nil {
+			t.Errorf("%q: %v", test.str, err)
+			continue
+		}
+		if got != test.want || got.String() != test.name {
+			t.Errorf("%q: got %+v (%s) want %+v (%s)", test.str, got, got, test.want, test.name)
+		}
+	}
+
+	for _, str := range []string{"0+5", "0d", "5", "blitz"} {
+		if _, err := ParseTimeControl(str); err == nil {
+			t.Errorf("%q: expected an error", str)
+		}
+	}
+}
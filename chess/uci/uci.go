@@ -0,0 +1,366 @@
+package uci
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sardap/chessbot/chess"
+)
+
+//defaultTimeout how long to wait for the engine when no timeout is set
+const defaultTimeout = 10 * time.Second
+
+//ErrTimeout the engine didn't answer in time
+var ErrTimeout = errors.New("engine didn't answer in time")
+
+//Options how to start an engine
+type Options struct {
+	//Path the engine binary
+	Path string
+	Args []string
+	//Env extra environment variables for the engine
+	Env []string
+	//Timeout how long to wait for an answer on top of any search time
+	Timeout time.Duration
+}
+
+//Score an engine's evaluation from the side to move's point of view
+type Score struct {
+	//Centipawns the score when there is no forced mate
+	Centipawns int
+	//Mate the moves until mate, negative when being mated
+	Mate int
+}
+
+//Info the search details an engine reports while thinking
+type Info struct {
+	Depth int
+	Nodes int64
+	Score Score
+	//PV the line the engine expects as UCI moves
+	PV []string
+}
+
+//Result the move an engine picked and its last report
+type Result struct {
+	BestMove string
+	Ponder   string
+	Info     Info
+}
+
+//Engine a running UCI engine
+type Engine struct {
+	//Name the name the engine gave itself
+	Name    string
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	lines   chan string
+	timeout time.Duration
+}
+
+//Start runs the engine and waits until it's ready for commands
+func Start(opts Options) (*Engine, error) {
+	cmd := exec.Command(opts.Path, opts.Args...)
+	cmd.Env = append(os.Environ(), opts.Env...)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, errors.Wrapf(err, "unable to start engine %s", opts.Path)
+	}
+
+	e := &Engine{
+		cmd:     cmd,
+		stdin:   stdin,
+		lines:   make(chan string, 64),
+		timeout: opts.Timeout,
+	}
+	if e.timeout <= 0 {
+		e.timeout = defaultTimeout
+	}
+
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			e.lines <- scanner.Text()
+		}
+		close(e.lines)
+	}()
+
+	if err := e.send("uci"); err != nil {
+		e.Close()
+		return nil, err
+	}
+	err = e.waitFor("uciok", e.timeout, func(line string) {
+		if strings.HasPrefix(line, "id name ") {
+			e.Name = strings.TrimPrefix(line, "id name ")
+		}
+	})
+	if err != nil {
+		e.Close()
+		return nil, errors.Wrap(err, "engine didn't start as a UCI engine")
+	}
+
+	if err := e.ready(); err != nil {
+		e.Close()
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (e *Engine) send(format string, args ...interface{}) error {
+	_, err := fmt.Fprintf(e.stdin, format+"\n", args...)
+	return err
+}
+
+//waitFor reads lines until one starts with the prefix passing every other line to seen
+func (e *Engine) waitFor(prefix string, timeout time.Duration, seen func(line string)) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case line, ok := <-e.lines:
+			if !ok {
+				return errors.New("engine exited")
+			}
+			if line == prefix || strings.HasPrefix(line, prefix+" ") {
+				if seen != nil && line != prefix {
+					seen(line)
+				}
+				return nil
+			}
+			if seen != nil {
+				seen(line)
+			}
+		case <-timer.C:
+			return ErrTimeout
+		}
+	}
+}
+
+//ready waits for the engine to finish whatever it was doing
+func (e *Engine) ready() error {
+	if err := e.send("isready"); err != nil {
+		return err
+	}
+
+	return e.waitFor("readyok", e.timeout, nil)
+}
+
+//SetOption sets one of the engine's options like Skill Level or Threads
+func (e *Engine) SetOption(name, value string) error {
+	if err := e.send("setoption name %s value %s", name, value); err != nil {
+		return err
+	}
+
+	return e.ready()
+}
+
+//NewGame tells the engine the next position is from a different game
+func (e *Engine) NewGame() error {
+	if err := e.send("ucinewgame"); err != nil {
+		return err
+	}
+
+	return e.ready()
+}
+
+//Position sets the position to search as a FEN and UCI moves played from it
+func (e *Engine) Position(fen string, moves []string) error {
+	cmd := "position startpos"
+	if fen != "" {
+		cmd = "position fen " + fen
+	}
+	if len(moves) > 0 {
+		cmd += " moves " + strings.Join(moves, " ")
+	}
+
+	return e.send(cmd)
+}
+
+//SetGame sets the position to the current position of the game
+func (e *Engine) SetGame(g *chess.Game) error {
+	moves := make([]string, len(g.Moves))
+	for i, mv := range g.Moves {
+		moves[i] = mv.UCI()
+	}
+
+	return e.Position(g.StartFEN, moves)
+}
+
+//Go searches the position for the time and returns the engine's move
+func (e *Engine) Go(moveTime time.Duration) (Result, error) {
+	if err := e.send("go movetime %d", moveTime.Milliseconds()); err != nil {
+		return Result{}, err
+	}
+
+	var result Result
+	seen := func(line string) {
+		if info, ok := ParseInfo(line); ok {
+			result.Info = info
+		}
+		if best, ponder, ok := ParseBestMove(line); ok {
+			result.BestMove, result.Ponder = best, ponder
+		}
+	}
+
+	err := e.waitFor("bestmove", moveTime+e.timeout, seen)
+	if err == ErrTimeout {
+		//Ask it to stop and give the move it has
+		if err := e.send("stop"); err != nil {
+			return Result{}, err
+		}
+		err = e.waitFor("bestmove", e.timeout, seen)
+	}
+	if err != nil {
+		return Result{}, err
+	}
+
+	if result.BestMove == "" || result.BestMove == "(none)" {
+		return result, errors.New("engine has no move to play")
+	}
+
+	return result, nil
+}
+
+//BestMove searches the game's current position and returns the engine's move
+func (e *Engine) BestMove(g *chess.Game, moveTime time.Duration) (chess.Move, Result, error) {
+	if err := e.SetGame(g); err != nil {
+		return chess.Move{}, Result{}, err
+	}
+
+	result, err := e.Go(moveTime)
+	if err != nil {
+		return chess.Move{}, result, err
+	}
+
+	mv, err := g.ParseMove(result.BestMove)
+	if err != nil {
+		return chess.Move{}, result, errors.Wrapf(err, "engine played %s", result.BestMove)
+	}
+
+	return mv, result, nil
+}
+
+//Analyser evaluates positions with the engine thinking for moveTime on each
+func (e *Engine) Analyser(moveTime time.Duration) chess.Analyser {
+	return func(g *chess.Game) (chess.Evaluation, error) {
+		mv, result, err := e.BestMove(g, moveTime)
+		if err != nil {
+			return chess.Evaluation{}, err
+		}
+
+		eval := chess.Evaluation{
+			Best:  mv,
+			Score: result.Info.Score.Centipawns,
+			Mate:  result.Info.Score.Mate,
+		}
+		//Engines score for the side to move
+		if g.Turn == chess.SideBlack {
+			eval.Score, eval.Mate = -eval.Score, -eval.Mate
+		}
+
+		return eval, nil
+	}
+}
+
+//Close asks the engine to quit and kills it if it doesn't
+func (e *Engine) Close() error {
+	e.send("quit")
+	e.stdin.Close()
+
+	//Keep reading so the engine isn't stuck writing
+	go func() {
+		for range e.lines {
+		}
+	}()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- e.cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(e.timeout):
+		e.cmd.Process.Kill()
+		return ErrTimeout
+	}
+}
+
+//ParseInfo reads an info line, ok is false for other lines or info
+//without a score like currmove updates
+func ParseInfo(line string) (Info, bool) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || fields[0] != "info" {
+		return Info{}, false
+	}
+
+	var result Info
+	hasScore := false
+	for i := 1; i < len(fields); i++ {
+		next := func() string {
+			if i+1 < len(fields) {
+				i++
+				return fields[i]
+			}
+			return ""
+		}
+
+		switch fields[i] {
+		case "depth":
+			result.Depth, _ = strconv.Atoi(next())
+		case "nodes":
+			result.Nodes, _ = strconv.ParseInt(next(), 10, 64)
+		case "score":
+			switch next() {
+			case "cp":
+				result.Score.Centipawns, _ = strconv.Atoi(next())
+				hasScore = true
+			case "mate":
+				result.Score.Mate, _ = strconv.Atoi(next())
+				hasScore = true
+			}
+		case "pv":
+			result.PV = fields[i+1:]
+			i = len(fields)
+		case "string":
+			//The rest of the line is free text
+			i = len(fields)
+		}
+	}
+
+	return result, hasScore
+}
+
+//ParseBestMove reads a bestmove line
+func ParseBestMove(line string) (string, string, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || fields[0] != "bestmove" {
+		return "", "", false
+	}
+
+	ponder := ""
+	if len(fields) >= 4 && fields[2] == "ponder" {
+		ponder = fields[3]
+	}
+
+	return fields[1], ponder, true
+}
@@ -0,0 +1,154 @@
+package uci
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sardap/chessbot/chess"
+)
+
+//fakeEngine starts this test binary as a small UCI engine, mode changes how it behaves
+func fakeEngine(t *testing.T, mode string) *Engine {
+	t.Helper()
+
+	engine, err := Start(Options{
+		Path:    os.Args[0],
+		Args:    []string{"-test.run=TestHelperProcess", "--"},
+		Env:     []string{"GO_WANT_HELPER_PROCESS=1", "FAKE_UCI_MODE=" + mode},
+		Timeout: time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return engine
+}
+
+//TestHelperProcess isn't a real test it's the fake engine
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	defer os.Exit(0)
+
+	mode := os.Getenv("FAKE_UCI_MODE")
+	position := ""
+	in := bufio.NewScanner(os.Stdin)
+	for in.Scan() {
+		fields := strings.Fields(in.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "uci":
+			fmt.Println("id name Fake Engine")
+			fmt.Println("id author chessbot")
+			fmt.Println("option name Skill Level type spin default 20 min 0 max 20")
+			fmt.Println("uciok")
+		case "isready":
+			fmt.Println("readyok")
+		case "position":
+			position = strings.Join(fields[1:], " ")
+		case "go":
+			if mode == "hang" {
+				continue
+			}
+			fmt.Println("info depth 1 score cp 13 nodes 20 pv e2e4")
+			fmt.Println("info currmove e2e4 currmovenumber 1")
+			if strings.HasSuffix(position, "e2e4") {
+				fmt.Println("info depth 2 score mate -3 nodes 400 pv e7e5 g1f3")
+				fmt.Println("bestmove e7e5 ponder g1f3")
+			} else {
+				fmt.Println("info depth 2 score cp 31 nodes 400 pv e2e4 e7e5")
+				fmt.Println("bestmove e2e4 ponder e7e5")
+			}
+		case "stop":
+			fmt.Println("bestmove a2a3")
+		case "quit":
+			return
+		}
+	}
+}
+
+func TestEngineSearch(t *testing.T) {
+	engine := fakeEngine(t, "")
+	defer engine.Close()
+
+	if engine.Name != "Fake Engine" {
+		t.Errorf("name got %q", engine.Name)
+	}
+
+	if err := engine.NewGame(); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.SetOption("Skill Level", "5"); err != nil {
+		t.Fatal(err)
+	}
+
+	game := chess.CreateGame("1", "2", "guild", color.RGBA{255, 255, 255, 255}, color.RGBA{0, 0, 0, 255})
+	mv, result, err := engine.BestMove(&game, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mv.UCI() != "e2e4" || result.Ponder != "e7e5" {
+		t.Errorf("got %s ponder %s want e2e4 ponder e7e5", mv.UCI(), result.Ponder)
+	}
+	if result.Info.Depth != 2 || result.Info.Score.Centipawns != 31 || result.Info.Nodes != 400 {
+		t.Errorf("info got %+v", result.Info)
+	}
+
+	game.MakeMove(mv)
+	_, result, err = engine.BestMove(&game, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.BestMove != "e7e5" || result.Info.Score.Mate != -3 {
+		t.Errorf("got %s %+v want e7e5 mate -3", result.BestMove, result.Info.Score)
+	}
+}
+
+func TestEngineStopsAfterTimeout(t *testing.T) {
+	engine := fakeEngine(t, "hang")
+	defer engine.Close()
+
+	if err := engine.Position("", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := engine.Go(10 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.BestMove != "a2a3" {
+		t.Errorf("got %s want the move given after stop", result.BestMove)
+	}
+}
+
+func TestStartRejectsMissingEngine(t *testing.T) {
+	if _, err := Start(Options{Path: "/does/not/exist"}); err == nil {
+		t.Error("expected an error starting a missing engine")
+	}
+}
+
+func TestParseInfo(t *testing.T) {
+	info, ok := ParseInfo("info depth 12 seldepth 18 multipv 1 score cp -45 upperbound nodes 123456 nps 1000 pv d2d4 g8f6 c2c4")
+	if !ok {
+		t.Fatal("info line with a score not read")
+	}
+	if info.Depth != 12 || info.Score.Centipawns != -45 || info.Nodes != 123456 || strings.Join(info.PV, " ") != "d2d4 g8f6 c2c4" {
+		t.Errorf("got %+v", info)
+	}
+
+	if _, ok := ParseInfo("info string NNUE evaluation enabled score cp 10"); ok {
+		t.Error("info string read as a score")
+	}
+	if _, ok := ParseInfo("bestmove e2e4"); ok {
+		t.Error("bestmove read as info")
+	}
+}
@@ -6,11 +6,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
 	"time"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
 	"github.com/sardap/chessbot/chess"
 	"github.com/sardap/chessbot/env"
+	"github.com/sardap/chessbot/rating"
 )
 
 //Instance DB Connection instance
@@ -37,32 +42,133 @@ func (i *Instance) Connect() {
 	}
 }
 
-//DeleteGame Deletes a game from the DB
-func (i *Instance) DeleteGame(g *chess.Game) error {
-	return i.db.Del(
-		context.TODO(),
-		g.ID(),
-	).Err()
+func gameKey(id string) string {
+	return fmt.Sprintf("game_%s", id)
+}
+
+func pairKey(pairID string) string {
+	return fmt.Sprintf("pair_%s", pairID)
+}
+
+func playerKey(guildID, playerID string) string {
+	return fmt.Sprintf("player_%s_%s", guildID, playerID)
+}
+
+//activeGamesKey the set of every game being played
+const activeGamesKey = "active_games"
+
+//gameIndexes the index sets a game's id is stored in
+func gameIndexes(g *chess.Game) []string {
+	return []string{
+		pairKey(g.PairID()),
+		playerKey(g.GuildID, g.White.ID),
+		playerKey(g.GuildID, g.Black.ID),
+	}
+}
+
+//ErrGameChanged the game was moved in or ended after it was loaded
+var ErrGameChanged = errors.New("the game changed while you were playing, try again")
+
+//storedVersion gets the version of the game saved in the DB watched by the
+//transaction, a game that isn't saved has version -1
+func storedVersion(ctx context.Context, tx *redis.Tx, id string) (int, error) {
+	res := tx.Get(ctx, gameKey(id))
+	if res.Err() == redis.Nil {
+		return -1, nil
+	} else if res.Err() != nil {
+		return 0, res.Err()
+	}
+
+	var stored struct {
+		Version int `json:"version"`
+	}
+	err := json.Unmarshal([]byte(res.Val()), &stored)
+
+	return stored.Version, err
+}
+
+//updateGame runs the update if the saved game is still the version the
+//caller loaded and nothing changes it in the meantime
+func (i *Instance) updateGame(
+	id string, version int, update func(ctx context.Context, pipe redis.Pipeliner),
+) error {
+	ctx := context.TODO()
+
+	err := i.db.Watch(ctx, func(tx *redis.Tx) error {
+		stored, err := storedVersion(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		//New games haven't been saved yet
+		if stored != version && !(stored == -1 && version == 0) {
+			return ErrGameChanged
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			update(ctx, pipe)
+			return nil
+		})
+		return err
+	}, gameKey(id))
+	if err == redis.TxFailedErr {
+		return ErrGameChanged
+	}
+
+	return err
+}
+
+//DeleteGame Deletes a game from the DB returning false if it was already
+//deleted, it fails with ErrGameChanged if the game was changed after it was
+//loaded
+func (i *Instance) DeleteGame(g *chess.Game) (bool, error) {
+	var removed *redis.IntCmd
+	err := i.updateGame(g.ID(), g.Version, func(ctx context.Context, pipe redis.Pipeliner) {
+		pipe.Del(ctx, gameKey(g.ID()))
+		removed = pipe.SRem(ctx, activeGamesKey, g.ID())
+		for _, index := range gameIndexes(g) {
+			pipe.SRem(ctx, index, g.ID())
+		}
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return removed.Val() > 0, nil
 }
 
-//SaveGame saves a game under the active section of the DB
+//SaveGame saves a game under the active section of the DB, it fails with
+//ErrGameChanged if the game was changed or ended after it was loaded
 func (i *Instance) SaveGame(g *chess.Game) error {
-	bytes, err := json.Marshal(*g)
+	saved := *g
+	saved.Version++
+
+	bytes, err := json.Marshal(saved)
 	if err != nil {
 		return err
 	}
 
-	return i.db.Set(
-		context.TODO(), g.ID(), bytes,
-		time.Duration(24)*time.Hour,
-	).Err()
+	//Games are kept until they finish, abandoned games are ended by the bot
+	err = i.updateGame(g.ID(), g.Version, func(ctx context.Context, pipe redis.Pipeliner) {
+		pipe.Set(ctx, gameKey(g.ID()), bytes, 0)
+		pipe.SAdd(ctx, activeGamesKey, g.ID())
+		for _, index := range gameIndexes(g) {
+			pipe.SAdd(ctx, index, g.ID())
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	g.Version = saved.Version
+	return nil
 }
 
 //GetGame gets a game from the DB
 func (i *Instance) GetGame(id string) (*chess.Game, error) {
 	ctx := context.TODO()
 
-	res := i.db.Get(ctx, id)
+	res := i.db.Get(ctx, gameKey(id))
 	if res.Err() != nil {
 		return nil, res.Err()
 	}
@@ -75,6 +181,314 @@ func (i *Instance) GetGame(id string) (*chess.Game, error) {
 	return &result, err
 }
 
+//legacyGamePattern matches the keys games were saved under before games
+//had their own id, which was the id of the two players
+const legacyGamePattern = "[0-9]*_[0-9]*_[0-9]*"
+
+var legacyGameRe = regexp.MustCompile("^[0-9]+_[0-9]+_[0-9]+$")
+
+//MigrateGames moves games saved under the two players' id to their own id
+//so they can be found again, returns how many were moved
+func (i *Instance) MigrateGames() (int, error) {
+	ctx := context.TODO()
+
+	count := 0
+	iter := i.db.Scan(ctx, 0, legacyGamePattern, 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		if !legacyGameRe.MatchString(key) {
+			continue
+		}
+
+		//Only one instance moves each game
+		err := i.db.Watch(ctx, func(tx *redis.Tx) error {
+			byts, err := tx.Get(ctx, key).Bytes()
+			if err != nil {
+				return err
+			}
+
+			var g chess.Game
+			if err := json.Unmarshal(byts, &g); err != nil {
+				return err
+			}
+			g.UID = chess.NewGameID()
+			g.Version = 1
+
+			byts, err = json.Marshal(g)
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Set(ctx, gameKey(g.ID()), byts, 0)
+				pipe.SAdd(ctx, activeGamesKey, g.ID())
+				for _, index := range gameIndexes(&g) {
+					pipe.SAdd(ctx, index, g.ID())
+				}
+				pipe.Del(ctx, key)
+				return nil
+			})
+			return err
+		}, key)
+		if err == redis.Nil || err == redis.TxFailedErr {
+			continue
+		} else if err != nil {
+			return count, errors.Wrapf(err, "unable to migrate game %s", key)
+		}
+		count++
+	}
+
+	return count, iter.Err()
+}
+
+//getIndexedGames gets every game in an index dropping ids whose game expired
+func (i *Instance) getIndexedGames(index string) ([]*chess.Game, error) {
+	ctx := context.TODO()
+
+	ids, err := i.db.SMembers(ctx, index).Result()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(ids)
+
+	games := make([]*chess.Game, 0, len(ids))
+	for _, id := range ids {
+		game, err := i.GetGame(id)
+		if err == redis.Nil {
+			i.db.SRem(ctx, index, id)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		games = append(games, game)
+	}
+
+	return games, nil
+}
+
+//GetPairGames gets the active games between two players in a guild
+func (i *Instance) GetPairGames(guildID, id1, id2 string) ([]*chess.Game, error) {
+	return i.getIndexedGames(pairKey(chess.PairID(guildID, id1, id2)))
+}
+
+//GetActiveGames gets every game being played
+func (i *Instance) GetActiveGames() ([]*chess.Game, error) {
+	return i.getIndexedGames(activeGamesKey)
+}
+
+//GetPlayerGames gets the active games a player has in a guild
+func (i *Instance) GetPlayerGames(guildID, playerID string) ([]*chess.Game, error) {
+	return i.getIndexedGames(playerKey(guildID, playerID))
+}
+
+//SaveChallenge saves a challenge until it expires
+func (i *Instance) SaveChallenge(c *chess.Challenge) error {
+	bytes, err := json.Marshal(*c)
+	if err != nil {
+		return err
+	}
+
+	return i.db.Set(
+		context.TODO(), c.ID(), bytes, time.Until(c.Expires),
+	).Err()
+}
+
+//GetChallenge gets a challenge that hasn't expired from the DB
+func (i *Instance) GetChallenge(id string) (*chess.Challenge, error) {
+	res := i.db.Get(context.TODO(), id)
+	if res.Err() != nil {
+		return nil, res.Err()
+	}
+
+	var result chess.Challenge
+	err := json.Unmarshal([]byte(res.Val()), &result)
+
+	return &result, err
+}
+
+//DeleteChallenge Deletes a challenge from the DB returning false if it was
+//already deleted
+func (i *Instance) DeleteChallenge(c *chess.Challenge) (bool, error) {
+	removed, err := i.db.Del(
+		context.TODO(),
+		c.ID(),
+	).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return removed > 0, nil
+}
+
+//GuildSettings options a guild's admins can change
+type GuildSettings struct {
+	//NoRatedHints stops players asking for hints in rated games
+	NoRatedHints bool `json:"no_rated_hints"`
+}
+
+func guildSettingsKey(guildID string) string {
+	return fmt.Sprintf("settings_%s", guildID)
+}
+
+//GetGuildSettings gets a guild's settings, the defaults if it has none
+func (i *Instance) GetGuildSettings(guildID string) (*GuildSettings, error) {
+	var result GuildSettings
+
+	res := i.db.Get(context.TODO(), guildSettingsKey(guildID))
+	if res.Err() == redis.Nil {
+		return &result, nil
+	} else if res.Err() != nil {
+		return nil, res.Err()
+	}
+
+	err := json.Unmarshal([]byte(res.Val()), &result)
+
+	return &result, err
+}
+
+//SaveGuildSettings saves a guild's settings
+func (i *Instance) SaveGuildSettings(guildID string, settings *GuildSettings) error {
+	bytes, err := json.Marshal(*settings)
+	if err != nil {
+		return err
+	}
+
+	return i.db.Set(context.TODO(), guildSettingsKey(guildID), bytes, 0).Err()
+}
+
+//GlobalRatings the scope of ratings across every guild
+const GlobalRatings = "global"
+
+func ratingKey(scope, playerID string) string {
+	return fmt.Sprintf("rating_%s_%s", scope, playerID)
+}
+
+//ratingsKey the sorted set of every rating in a scope
+func ratingsKey(scope string) string {
+	return fmt.Sprintf("ratings_%s", scope)
+}
+
+//GetRating gets a player's rating in a guild or GlobalRatings, new players
+//get the starting rating
+func (i *Instance) GetRating(scope, playerID string) (rating.Rating, error) {
+	return parseRating(i.db.Get(context.TODO(), ratingKey(scope, playerID)))
+}
+
+//parseRating reads a rating loaded from the DB, a missing rating is the
+//starting rating
+func parseRating(res *redis.StringCmd) (rating.Rating, error) {
+	if res.Err() == redis.Nil {
+		return rating.New(), nil
+	} else if res.Err() != nil {
+		return rating.Rating{}, res.Err()
+	}
+
+	var result rating.Rating
+	err := json.Unmarshal([]byte(res.Val()), &result)
+
+	return result, err
+}
+
+//ratingRetries how many times updating ratings is tried when another game
+//changes them first
+const ratingRetries = 10
+
+//RatingUpdate works out two players' new ratings from their current ones
+type RatingUpdate func(white, black rating.Rating) (rating.Rating, rating.Rating)
+
+//UpdateRatings updates two players' ratings in a guild or GlobalRatings
+//without losing an update made by another game at the same time, returns
+//the new ratings
+func (i *Instance) UpdateRatings(
+	scope, whiteID, blackID string, update RatingUpdate,
+) (rating.Rating, rating.Rating, error) {
+	ctx := context.TODO()
+	whiteKey, blackKey := ratingKey(scope, whiteID), ratingKey(scope, blackID)
+
+	var newWhite, newBlack rating.Rating
+	for try := 0; try < ratingRetries; try++ {
+		err := i.db.Watch(ctx, func(tx *redis.Tx) error {
+			white, err := parseRating(tx.Get(ctx, whiteKey))
+			if err != nil {
+				return err
+			}
+
+			black, err := parseRating(tx.Get(ctx, blackKey))
+			if err != nil {
+				return err
+			}
+
+			newWhite, newBlack = update(white, black)
+
+			whiteBytes, err := json.Marshal(newWhite)
+			if err != nil {
+				return err
+			}
+			blackBytes, err := json.Marshal(newBlack)
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Set(ctx, whiteKey, whiteBytes, 0)
+				pipe.Set(ctx, blackKey, blackBytes, 0)
+				pipe.ZAdd(ctx, ratingsKey(scope),
+					&redis.Z{Score: newWhite.Rating, Member: whiteID},
+					&redis.Z{Score: newBlack.Rating, Member: blackID},
+				)
+				return nil
+			})
+			return err
+		}, whiteKey, blackKey)
+		if err == redis.TxFailedErr {
+			continue
+		}
+
+		return newWhite, newBlack, err
+	}
+
+	return rating.Rating{}, rating.Rating{}, errors.New("ratings kept changing, try again later")
+}
+
+func archivedKey(gameID string) string {
+	return fmt.Sprintf("archived_%s", gameID)
+}
+
+func lastArchivedKey(pairID string) string {
+	return fmt.Sprintf("last_archived_%s", pairID)
+}
+
+//playerArchivesKey the sorted set of a player's archived games in a guild
+//scored by when they finished
+func playerArchivesKey(guildID, playerID string) string {
+	return fmt.Sprintf("archives_%s_%s", guildID, playerID)
+}
+
+//winsKey the sorted set of how many games each player in a guild has won
+func winsKey(guildID string) string {
+	return fmt.Sprintf("wins_%s", guildID)
+}
+
+//archivePattern matches archive ids when scanning the DB
+const archivePattern = "[0-9][0-9][0-9][0-9]:[0-9][0-9]:[0-9][0-9]-*"
+
+//indexArchive adds an archived game to the indexes it can be queried by
+func indexArchive(ctx context.Context, pipe redis.Pipeliner, archiveID string, g *chess.Game, at time.Time) {
+	for _, player := range []chess.Player{g.White, g.Black} {
+		pipe.ZAdd(ctx, playerArchivesKey(g.GuildID, player.ID), &redis.Z{
+			Score: float64(at.Unix()), Member: archiveID,
+		})
+	}
+
+	//The computer doesn't go on the leaderboard
+	if winner := g.Result.Winner(); winner != chess.SideEmpty {
+		if player := g.GetSidePlayer(winner); player.Computer == chess.DifficultyNone {
+			pipe.ZIncrBy(ctx, winsKey(g.GuildID), 1, player.ID)
+		}
+	}
+}
+
 //ArchiveGame archives a game in the DB
 func (i *Instance) ArchiveGame(g *chess.Game) error {
 	byts, err := json.Marshal(*g)
@@ -90,8 +504,218 @@ func (i *Instance) ArchiveGame(g *chess.Game) error {
 	}
 	gz.Close()
 
-	id := fmt.Sprintf("%s_%s", time.Now().UTC().Format("2006:01:02-15:04:05"), g.ID())
-	i.db.Set(context.TODO(), id, b.Bytes(), 0)
+	ctx := context.TODO()
 
-	return nil
+	now := time.Now().UTC()
+	id := g.ArchiveID(now)
+
+	pipe := i.db.TxPipeline()
+	pipe.Set(ctx, id, b.Bytes(), 0)
+	pipe.Set(ctx, archivedKey(g.ID()), id, 0)
+	pipe.Set(ctx, lastArchivedKey(g.PairID()), g.ID(), 0)
+	indexArchive(ctx, pipe, id, g, now)
+
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+//legacyArchive the winner of games archived before results were recorded
+type legacyArchive struct {
+	Winner chess.SideType `json:"win"`
+}
+
+//GetArchive loads and unzips an archived game by its archive id
+func (i *Instance) GetArchive(archiveID string) (*chess.Game, error) {
+	byts, err := i.db.Get(context.TODO(), archiveID).Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(byts))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	byts, err = ioutil.ReadAll(gz)
+	if err != nil {
+		return nil, err
+	}
+
+	var result chess.Game
+	if err := json.Unmarshal(byts, &result); err != nil {
+		return nil, err
+	}
+
+	//Games could only be resigned before results were recorded
+	var legacy legacyArchive
+	if !result.Over() && json.Unmarshal(byts, &legacy) == nil && legacy.Winner != chess.SideEmpty {
+		result.Result = chess.WinFor(legacy.Winner, chess.ReasonResignation)
+	}
+
+	result.ProcessMoves()
+
+	return &result, nil
+}
+
+//ReindexArchives indexes games archived before they were indexed, returns
+//how many were added
+func (i *Instance) ReindexArchives() (int, error) {
+	ctx := context.TODO()
+
+	count := 0
+	iter := i.db.Scan(ctx, 0, archivePattern, 100).Iterator()
+	for iter.Next(ctx) {
+		archiveID := iter.Val()
+
+		at, err := chess.ArchiveTime(archiveID)
+		if err != nil {
+			continue
+		}
+
+		g, err := i.GetArchive(archiveID)
+		if err != nil {
+			continue
+		}
+
+		err = i.db.ZScore(ctx, playerArchivesKey(g.GuildID, g.White.ID), archiveID).Err()
+		if err == nil {
+			continue
+		} else if err != redis.Nil {
+			return count, err
+		}
+
+		pipe := i.db.TxPipeline()
+		pipe.SetNX(ctx, archivedKey(g.ID()), archiveID, 0)
+		indexArchive(ctx, pipe, archiveID, g, at)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, iter.Err()
+}
+
+//GetArchivedGame gets a finished game by its game id
+func (i *Instance) GetArchivedGame(gameID string) (*chess.Game, error) {
+	archiveID, err := i.db.Get(context.TODO(), archivedKey(gameID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	return i.GetArchive(archiveID)
+}
+
+//GetLastArchivedGame gets the last game two players finished in a guild
+func (i *Instance) GetLastArchivedGame(guildID, id1, id2 string) (*chess.Game, error) {
+	gameID, err := i.db.Get(
+		context.TODO(), lastArchivedKey(chess.PairID(guildID, id1, id2)),
+	).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	return i.GetArchivedGame(gameID)
+}
+
+//GetPlayerArchives gets the ids of a player's archived games in a guild
+//newest first, start and stop are inclusive like redis ranges
+func (i *Instance) GetPlayerArchives(guildID, playerID string, start, stop int64) ([]string, error) {
+	return i.db.ZRevRange(
+		context.TODO(), playerArchivesKey(guildID, playerID), start, stop,
+	).Result()
+}
+
+//CountPlayerArchives counts a player's archived games in a guild
+func (i *Instance) CountPlayerArchives(guildID, playerID string) (int64, error) {
+	return i.db.ZCard(context.TODO(), playerArchivesKey(guildID, playerID)).Result()
+}
+
+//GetPlayerArchivedGames gets every archived game a player has in a guild
+//newest first
+func (i *Instance) GetPlayerArchivedGames(guildID, playerID string) ([]*chess.Game, error) {
+	ids, err := i.GetPlayerArchives(guildID, playerID, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	games := make([]*chess.Game, 0, len(ids))
+	for _, id := range ids {
+		game, err := i.GetArchive(id)
+		if err == redis.Nil {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		games = append(games, game)
+	}
+
+	return games, nil
+}
+
+//LeaderboardEntry a player's place on a leaderboard
+type LeaderboardEntry struct {
+	PlayerID string
+	Score    float64
+}
+
+//getLeaderboard gets the count highest scores in a sorted set
+func (i *Instance) getLeaderboard(key string, count int64) ([]LeaderboardEntry, error) {
+	scores, err := i.db.ZRevRangeWithScores(context.TODO(), key, 0, count-1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]LeaderboardEntry, len(scores))
+	for j, score := range scores {
+		result[j] = LeaderboardEntry{PlayerID: fmt.Sprint(score.Member), Score: score.Score}
+	}
+
+	return result, nil
+}
+
+//GetRatingLeaderboard gets the count highest rated players in a guild or GlobalRatings
+func (i *Instance) GetRatingLeaderboard(scope string, count int64) ([]LeaderboardEntry, error) {
+	return i.getLeaderboard(ratingsKey(scope), count)
+}
+
+//GetWinsLeaderboard gets the count players with the most wins in a guild
+func (i *Instance) GetWinsLeaderboard(guildID string, count int64) ([]LeaderboardEntry, error) {
+	return i.getLeaderboard(winsKey(guildID), count)
+}
+
+//HistoryPage a page of a player's history that its buttons can change
+type HistoryPage struct {
+	GuildID  string `json:"gid"`
+	PlayerID string `json:"player"`
+	Page     int    `json:"page"`
+}
+
+func historyPageKey(messageID string) string {
+	return fmt.Sprintf("history_%s", messageID)
+}
+
+//SaveHistoryPage saves the page a history message is showing until it expires
+func (i *Instance) SaveHistoryPage(messageID string, page *HistoryPage, expiry time.Duration) error {
+	bytes, err := json.Marshal(*page)
+	if err != nil {
+		return err
+	}
+
+	return i.db.Set(context.TODO(), historyPageKey(messageID), bytes, expiry).Err()
+}
+
+//GetHistoryPage gets the page a history message is showing
+func (i *Instance) GetHistoryPage(messageID string) (*HistoryPage, error) {
+	res := i.db.Get(context.TODO(), historyPageKey(messageID))
+	if res.Err() != nil {
+		return nil, res.Err()
+	}
+
+	var result HistoryPage
+	err := json.Unmarshal([]byte(res.Val()), &result)
+
+	return &result, err
 }
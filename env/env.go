@@ -5,6 +5,7 @@ import (
 	"image/jpeg"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -20,8 +21,33 @@ var (
 	RedisDB int
 	//CmdPrefix the command prefix
 	CmdPrefix string
+	//ReminderAfter how long a slow game can wait on a player before they are reminded
+	ReminderAfter time.Duration
+	//AbandonAfter how long an untimed game can go without a move before it's aborted
+	AbandonAfter time.Duration
+	//UCIEnginePath an external UCI engine used for hard computer games, empty uses the built in engine
+	UCIEnginePath string
+	//UCIMoveTime how long the external engine thinks about each move
+	UCIMoveTime time.Duration
+	//UCITimeout how long to wait for the external engine to answer
+	UCITimeout time.Duration
 )
 
+//durationEnv reads a duration like 12h from the env var or uses the default
+func durationEnv(name string, def time.Duration) time.Duration {
+	str := os.Getenv(name)
+	if str == "" {
+		return def
+	}
+
+	result, err := time.ParseDuration(str)
+	if err != nil {
+		panic(errors.Wrapf(err, "Error reading %s", name))
+	}
+
+	return result
+}
+
 func init() {
 	imgQualityStr := os.Getenv("IMG_QUALITY")
 	if imgQualityStr == "" {
@@ -43,4 +69,11 @@ func init() {
 
 	CmdPrefix = os.Getenv("CMD_PREFIX")
 	fmt.Printf("%s\n", CmdPrefix)
+
+	ReminderAfter = durationEnv("REMINDER_AFTER", 12*time.Hour)
+	AbandonAfter = durationEnv("ABANDON_AFTER", 7*24*time.Hour)
+
+	UCIEnginePath = os.Getenv("UCI_ENGINE_PATH")
+	UCIMoveTime = durationEnv("UCI_MOVE_TIME", 2*time.Second)
+	UCITimeout = durationEnv("UCI_TIMEOUT", 10*time.Second)
 }
@@ -4,7 +4,7 @@ go 1.15
 
 require (
 	github.com/DaoYoung/gen-model v1.0.0 // indirect
-	github.com/bwmarrin/discordgo v0.22.0
+	github.com/bwmarrin/discordgo v0.27.1
 	github.com/go-redis/redis/v8 v8.4.2
 	github.com/icza/gox v0.0.0-20200702115100-7dc3510ae515
 	github.com/jinzhu/gorm v1.9.16 // indirect
@@ -17,7 +17,6 @@ require (
 	github.com/spf13/afero v1.4.1 // indirect
 	github.com/spf13/cobra v1.1.1 // indirect
 	github.com/spf13/viper v1.7.1 // indirect
-	golang.org/x/crypto v0.0.0-20201208171446-5f87f3452ae9 // indirect
 	golang.org/x/sys v0.0.0-20201207223542-d4d67f95c62d // indirect
 	golang.org/x/text v0.3.4 // indirect
 	gopkg.in/ini.v1 v1.62.0 // indirect
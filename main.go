@@ -3,44 +3,129 @@ package main
 import (
 	"fmt"
 	"image/color"
+	"io"
+	"io/ioutil"
 	"log"
 	"math/rand"
+	"net/http"
 	"os"
 	"os/signal"
 	"regexp"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/icza/gox/imagex/colorx"
+	"github.com/pkg/errors"
 	"github.com/sardap/chessbot/chess"
+	"github.com/sardap/chessbot/chess/uci"
 	"github.com/sardap/chessbot/db"
 	"github.com/sardap/chessbot/env"
+	"github.com/sardap/chessbot/rating"
 	"github.com/sardap/discom"
 )
 
+//maxAttachmentSize the largest attached file that will be read
+const maxAttachmentSize = 1 << 20
+
+//attachmentClient downloads attached files giving up on stalled downloads
+var attachmentClient = &http.Client{Timeout: 30 * time.Second}
+
+var errMissingGame = errors.New("game doesn't exist")
+
+//challengeExpiry how long a challenge waits to be accepted
+const challengeExpiry = 10 * time.Minute
+
+//analyseMoveTime how long the engine looks at each position when analysing
+const analyseMoveTime = 300 * time.Millisecond
+
+//maxMessageLength the longest message discord will send
+const maxMessageLength = 2000
+
+//leaderboardSize how many players are shown on a leaderboard
+const leaderboardSize = 10
+
+//favouriteOpenings how many openings are shown in a player's stats
+const favouriteOpenings = 3
+
+//historyPageSize how many games are shown on each page of a player's history
+const historyPageSize = 10
+
+//historyExpiry how long a history message can be paged through
+const historyExpiry = 30 * time.Minute
+
+//previousPageID and nextPageID the buttons that page through history
+const previousPageID = "history_previous"
+const nextPageID = "history_next"
+
+//sweepInterval how often games are checked for flag falls and reminders
+const sweepInterval = 30 * time.Second
+
 const infoPattern = "info$"
 const codeInfoPattern = "code info$"
-const startGamePattern = "<@!(?P<target>\\d{18})> .*?start ?((?P<white_color>#[0-9a-f]{6}) ?(?P<black_color>#[0-9a-f]{6}))? ?$"
-const getGamePattern = "<@!(?P<target>\\d{18})> .*?get$"
-const getMovesPattern = "<@!(?P<target>\\d{18})> .*?get .*?moves?$"
-const movePattern = "<@!(?P<target>\\d{18})> .*?move .*?([a-h][1-8]) .*?([a-h][1-8]) ?$"
-const castlingPattern = "<@!(?P<target>\\d{18})> .*?castling .*?([a-h][1-8]) .*?([a-h][1-8]) ([a-h][1-8]) ([a-h][1-8]) ?$"
-const enPassantPattern = "<@!(?P<target>\\d{18})> .*?en .*?passant .*?([a-h][1-8]) ?$"
-const promotionPattern = "<@!(?P<target>\\d{18})> .*?move .*?promotion .*?([a-h][1-8]) .*?([a-h][1-8]) (rook|knight|queen|bishop) ?$"
-const resginPattern = "<@!(?P<target>\\d{18})> .*?(resign|resgin)$"
+const listGamesPattern = "(?:my )?games$"
+const targetPattern = "<@!?(?P<target>\\d+)>"
+const challengePattern = targetPattern + " .*?(?:challenge|start)" +
+	"(?: +(?P<side>white|black|random))?" +
+	"(?: +(?P<time>\\d+\\+\\d+|\\d+ ?d(?:ays?)?|none))?" +
+	"(?: +(?P<level>easy|medium|hard))?" +
+	"(?: +(?P<white_color>#[0-9a-f]{6}) ?(?P<black_color>#[0-9a-f]{6}))? *$"
+const acceptChallengePattern = targetPattern + " .*?accept$"
+const declineChallengePattern = targetPattern + " .*?decline$"
+const cancelChallengePattern = targetPattern + " .*?cancel$"
+const gamePattern = targetPattern + "(?: +#?(?P<game>[0-9a-f]{8}))?"
+const getGamePattern = gamePattern + " .*?get$"
+const getMovesPattern = gamePattern + " .*?get .*?moves?$"
+const getFENPattern = gamePattern + " .*?get .*?fen$"
+const getPGNPattern = gamePattern + " .*?get .*?pgn$"
+const movePattern = gamePattern + " .*?(?:move|castle) +(?P<move>.+?) *$"
+const loadPGNPattern = "<@!?(?P<white>\\d+)> <@!?(?P<black>\\d+)> .*?load pgn(?s:(?P<pgn>.*))$"
+const resginPattern = gamePattern + " .*?(resign|resgin)$"
+const offerDrawPattern = gamePattern + " .*?offer draw$"
+const acceptDrawPattern = gamePattern + " .*?accept draw$"
+const declineDrawPattern = gamePattern + " .*?decline draw$"
+const requestTakebackPattern = gamePattern + " .*?request takeback$"
+const acceptTakebackPattern = gamePattern + " .*?accept takeback$"
+const rejectTakebackPattern = gamePattern + " .*?reject takeback$"
+const analysePattern = gamePattern + " .*?(?:analyse|analyze)$"
+const hintPattern = gamePattern + " .*?hint$"
+const ratedHintsPattern = "rated hints (?P<setting>on|off)$"
+const ratingPattern = "rating(?: +<@!?(?P<target>\\d+)>)?$"
+const leaderboardPattern = "leaderboard(?: +(?P<by>wins))?$"
+const historyPattern = "history(?: +<@!?(?P<target>\\d+)>)?$"
+const replayPattern = "replay +`?(?P<archive>[^` ]+)`?$"
+const statsPattern = "stats(?: +<@!?(?P<target>\\d+)>)?(?: +(?:vs|against) +<@!?(?P<opponent>\\d+)>)?$"
 
 var (
-	commandSet  *discom.CommandSet
-	startGameRe = regexp.MustCompile(startGamePattern)
-	getGameRe   = regexp.MustCompile(getGamePattern)
-	getMovesRe  = regexp.MustCompile(getMovesPattern)
-	moveRe      = regexp.MustCompile(movePattern)
-	castlingRe  = regexp.MustCompile(castlingPattern)
-	enPassantRe = regexp.MustCompile(enPassantPattern)
-	promotionRe = regexp.MustCompile(promotionPattern)
-	resginRe    = regexp.MustCompile(resginPattern)
-	dbIns       *db.Instance
+	commandSet         *discom.CommandSet
+	challengeRe        = regexp.MustCompile(challengePattern)
+	acceptChallengeRe  = regexp.MustCompile(acceptChallengePattern)
+	declineChallengeRe = regexp.MustCompile(declineChallengePattern)
+	cancelChallengeRe  = regexp.MustCompile(cancelChallengePattern)
+	getGameRe          = regexp.MustCompile(getGamePattern)
+	getMovesRe         = regexp.MustCompile(getMovesPattern)
+	getFENRe           = regexp.MustCompile(getFENPattern)
+	getPGNRe           = regexp.MustCompile(getPGNPattern)
+	moveRe             = regexp.MustCompile("(?i)" + movePattern)
+	loadPGNRe          = regexp.MustCompile("(?i)" + loadPGNPattern)
+	resginRe           = regexp.MustCompile(resginPattern)
+	offerDrawRe        = regexp.MustCompile(offerDrawPattern)
+	acceptDrawRe       = regexp.MustCompile(acceptDrawPattern)
+	declineDrawRe      = regexp.MustCompile(declineDrawPattern)
+	requestTakebackRe  = regexp.MustCompile(requestTakebackPattern)
+	acceptTakebackRe   = regexp.MustCompile(acceptTakebackPattern)
+	rejectTakebackRe   = regexp.MustCompile(rejectTakebackPattern)
+	analyseRe          = regexp.MustCompile(analysePattern)
+	hintRe             = regexp.MustCompile(hintPattern)
+	ratedHintsRe       = regexp.MustCompile(ratedHintsPattern)
+	ratingRe           = regexp.MustCompile(ratingPattern)
+	leaderboardRe      = regexp.MustCompile(leaderboardPattern)
+	statsRe            = regexp.MustCompile(statsPattern)
+	historyRe          = regexp.MustCompile(historyPattern)
+	replayRe           = regexp.MustCompile(replayPattern)
+	archiveIDRe        = regexp.MustCompile(chess.ArchiveIDPattern)
+	dbIns              *db.Instance
 )
 
 func init() {
@@ -66,9 +151,55 @@ func init() {
 	}
 
 	err = commandSet.AddCommand(discom.Command{
-		Re: regexp.MustCompile(startGamePattern), Handler: startGameCmd,
-		Example:     "@TARGET_PLAYER start",
-		Description: "Start game with the target player (you can only have a single game going with a player per server)",
+		Re: regexp.MustCompile(listGamesPattern), Handler: listGamesCmd,
+		Example: "games", Description: "Lists your games going in this server with their ids",
+		CaseInSense: true,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	err = commandSet.AddCommand(discom.Command{
+		Re: regexp.MustCompile(challengePattern), Handler: challengeCmd,
+		Example:     "@TARGET_PLAYER challenge white 5+3",
+		Description: "Challenge the target player to a game, optionally pick white, black or random and a time control like 5+3 or 3d. Challenge the bot with easy, medium or hard to play the computer",
+		CaseInSense: true,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	err = commandSet.AddCommand(discom.Command{
+		Re: regexp.MustCompile(acceptChallengePattern), Handler: acceptChallengeCmd,
+		Example: "@CHALLENGER accept", Description: "Accept a challenge and start the game",
+		CaseInSense: true,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	err = commandSet.AddCommand(discom.Command{
+		Re: regexp.MustCompile(declineChallengePattern), Handler: declineChallengeCmd,
+		Example: "@CHALLENGER decline", Description: "Decline a challenge",
+		CaseInSense: true,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	err = commandSet.AddCommand(discom.Command{
+		Re: regexp.MustCompile(cancelChallengePattern), Handler: cancelChallengeCmd,
+		Example: "@TARGET_PLAYER cancel", Description: "Cancel a challenge you sent",
+		CaseInSense: true,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	err = commandSet.AddCommand(discom.Command{
+		Re: regexp.MustCompile(loadPGNPattern), Handler: loadPGNCmd,
+		Example:     "@WHITE_PLAYER @BLACK_PLAYER load pgn 1. e4 e5 2. Nf3",
+		Description: "Load a game from a PGN pasted after the command or attached as a file, the other player has to accept to play on",
 		CaseInSense: true,
 	})
 	if err != nil {
@@ -95,10 +226,30 @@ func init() {
 		panic(err)
 	}
 
+	err = commandSet.AddCommand(discom.Command{
+		Re: regexp.MustCompile(getFENPattern), Handler: getFENCmd,
+		Example:     "@TARGET_PLAYER get fen",
+		Description: "Prints the current position as a FEN string",
+		CaseInSense: true,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	err = commandSet.AddCommand(discom.Command{
+		Re: regexp.MustCompile(getPGNPattern), Handler: getPGNCmd,
+		Example:     "@TARGET_PLAYER get pgn",
+		Description: "Sends the game as a PGN file for use in other chess software",
+		CaseInSense: true,
+	})
+	if err != nil {
+		panic(err)
+	}
+
 	err = commandSet.AddCommand(discom.Command{
 		Re: regexp.MustCompile(movePattern), Handler: moveCmd,
 		Example:     "@TARGET_PLAYER move F2 F4",
-		Description: "Move a piece in a target game it uses the letters and numbers grid thing",
+		Description: "Move a piece in a target game using two cells (e2 e4), UCI (e2e4, e7e8q) or SAN (Nf3, exd5, O-O, e8=Q)",
 		CaseInSense: true,
 	})
 	if err != nil {
@@ -106,9 +257,8 @@ func init() {
 	}
 
 	err = commandSet.AddCommand(discom.Command{
-		Re: regexp.MustCompile(castlingPattern), Handler: castlingCmd,
-		Example:     "@TARGET_PLAYER castling A1 D1 D1 A1",
-		Description: "Perform a castling action it goes REMEMBER THIS HAS NO RULE CHECKING",
+		Re: regexp.MustCompile(resginPattern), Handler: resginCmd,
+		Example: "@TARGET_PLAYER resgin", Description: "Resign from a target game",
 		CaseInSense: true,
 	})
 	if err != nil {
@@ -116,9 +266,9 @@ func init() {
 	}
 
 	err = commandSet.AddCommand(discom.Command{
-		Re: regexp.MustCompile(enPassantPattern), Handler: enPassantCmd,
-		Example:     "@TARGET_PLAYER en passant A1 A2",
-		Description: "Perform a En Passant action it goes this will remove the piece that was En Passanted NOT MOVE it",
+		Re: regexp.MustCompile(offerDrawPattern), Handler: offerDrawCmd,
+		Example:     "@TARGET_PLAYER offer draw",
+		Description: "Offer a draw in a target game, the offer is withdrawn when you make your next move",
 		CaseInSense: true,
 	})
 	if err != nil {
@@ -126,9 +276,8 @@ func init() {
 	}
 
 	err = commandSet.AddCommand(discom.Command{
-		Re: regexp.MustCompile(promotionPattern), Handler: movePromotionCmd,
-		Example:     "@TARGET_PLAYER move promotion A1 A2 rook",
-		Description: "Perform a pawn promotion valid values are rook, knight, queen, bishop",
+		Re: regexp.MustCompile(acceptDrawPattern), Handler: acceptDrawCmd,
+		Example: "@TARGET_PLAYER accept draw", Description: "Accept a draw offered in a target game",
 		CaseInSense: true,
 	})
 	if err != nil {
@@ -136,8 +285,110 @@ func init() {
 	}
 
 	err = commandSet.AddCommand(discom.Command{
-		Re: regexp.MustCompile(resginPattern), Handler: resginCmd,
-		Example: "@TARGET_PLAYER resgin", Description: "Resign from a target game",
+		Re: regexp.MustCompile(declineDrawPattern), Handler: declineDrawCmd,
+		Example: "@TARGET_PLAYER decline draw", Description: "Decline a draw offered in a target game",
+		CaseInSense: true,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	err = commandSet.AddCommand(discom.Command{
+		Re: regexp.MustCompile(requestTakebackPattern), Handler: requestTakebackCmd,
+		Example:     "@TARGET_PLAYER request takeback",
+		Description: "Ask the target player to let you take back your last move",
+		CaseInSense: true,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	err = commandSet.AddCommand(discom.Command{
+		Re: regexp.MustCompile(acceptTakebackPattern), Handler: acceptTakebackCmd,
+		Example: "@TARGET_PLAYER accept takeback", Description: "Let the target player take back their last move",
+		CaseInSense: true,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	err = commandSet.AddCommand(discom.Command{
+		Re: regexp.MustCompile(rejectTakebackPattern), Handler: rejectTakebackCmd,
+		Example: "@TARGET_PLAYER reject takeback", Description: "Refuse the target player's takeback request",
+		CaseInSense: true,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	err = commandSet.AddCommand(discom.Command{
+		Re: regexp.MustCompile(analysePattern), Handler: analyseCmd,
+		Example:     "@TARGET_PLAYER analyse",
+		Description: "Find the mistakes in your last finished game with the target player",
+		CaseInSense: true,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	err = commandSet.AddCommand(discom.Command{
+		Re: regexp.MustCompile(hintPattern), Handler: hintCmd,
+		Example: "@TARGET_PLAYER hint", Description: "Get a suggested move sent to you by DM",
+		CaseInSense: true,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	err = commandSet.AddCommand(discom.Command{
+		Re: regexp.MustCompile(ratedHintsPattern), Handler: ratedHintsCmd,
+		Example: "rated hints off", Description: "Allow or stop hints in rated games, needs manage server",
+		CaseInSense: true,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	err = commandSet.AddCommand(discom.Command{
+		Re: regexp.MustCompile(ratingPattern), Handler: ratingCmd,
+		Example: "rating @TARGET_PLAYER", Description: "Show your rating or the target player's",
+		CaseInSense: true,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	err = commandSet.AddCommand(discom.Command{
+		Re: regexp.MustCompile(leaderboardPattern), Handler: leaderboardCmd,
+		Example: "leaderboard wins", Description: "Show the top players in the server by rating or wins",
+		CaseInSense: true,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	err = commandSet.AddCommand(discom.Command{
+		Re: regexp.MustCompile(statsPattern), Handler: statsCmd,
+		Example:     "stats @TARGET_PLAYER vs @OTHER_PLAYER",
+		Description: "Show a player's record, openings and their record against another player",
+		CaseInSense: true,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	err = commandSet.AddCommand(discom.Command{
+		Re: regexp.MustCompile(historyPattern), Handler: historyCmd,
+		Example: "history @TARGET_PLAYER", Description: "List a player's finished games",
+		CaseInSense: true,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	err = commandSet.AddCommand(discom.Command{
+		Re: regexp.MustCompile(replayPattern), Handler: replayCmd,
+		Example: "replay ARCHIVE_ID", Description: "Send the gif and PGN of a finished game from history",
 		CaseInSense: true,
 	})
 	if err != nil {
@@ -146,10 +397,14 @@ func init() {
 }
 
 func sendGame(s *discordgo.Session, channelID, msg string, game *chess.Game) {
+	if game.TimeControl.Timed() {
+		msg += "\n" + clockMsg(game)
+	}
+
 	s.ChannelMessageSendComplex(
 		channelID,
 		&discordgo.MessageSend{
-			Content: msg,
+			Content: fmt.Sprintf("%s\nGame ID: `%s`", msg, game.ID()),
 			Files: []*discordgo.File{{
 				Name:   fmt.Sprintf("%s.png", game.ID()),
 				Reader: game.CreateImage(),
@@ -158,15 +413,139 @@ func sendGame(s *discordgo.Session, channelID, msg string, game *chess.Game) {
 	)
 }
 
+//clockMsg shows how long each player has left
+func clockMsg(game *chess.Game) string {
+	now := time.Now().UTC()
+
+	return fmt.Sprintf(
+		"⏱️ %s White %s | Black %s",
+		game.TimeControl,
+		chess.FormatClock(game.Remaining(chess.SideWhite, now)),
+		chess.FormatClock(game.Remaining(chess.SideBlack, now)),
+	)
+}
+
+//endIfFlagged ends the game if the player to move has run out of time
+func endIfFlagged(s *discordgo.Session, channelID string, game *chess.Game) bool {
+	if !game.CheckFlag(time.Now().UTC()) {
+		return false
+	}
+
+	msg := fmt.Sprintf(
+		"Match between <@!%s>: %s and <@!%s>: %s Final State\n%s",
+		game.White.ID, game.White.Side.String(), game.Black.ID, game.Black.Side.String(),
+		resultMsg(game),
+	)
+	endGame(s, channelID, game, msg)
+	return true
+}
+
+//reminderDue returns true if the player to move should be reminded
+func reminderDue(game *chess.Game, now time.Time) bool {
+	//Live games are over long before a reminder would help
+	if game.Reminded || (game.TimeControl.Timed() && !game.TimeControl.Correspondence()) {
+		return false
+	}
+
+	after := env.ReminderAfter
+	if game.TimeControl.Correspondence() && game.TimeControl.PerMove/2 < after {
+		after = game.TimeControl.PerMove / 2
+	}
+
+	return now.Sub(game.LastMoveTime()) >= after
+}
+
+//remindPlayer DMs the player to move or pings them in the game's channel
+func remindPlayer(s *discordgo.Session, game *chess.Game, now time.Time) {
+	player := game.GetSidePlayer(game.Turn)
+	opponent := game.GetOpponent(player.ID)
+
+	msg := fmt.Sprintf("It's your move against <@!%s> in game `%s`", opponent.ID, game.ID())
+	if game.TimeControl.Correspondence() {
+		msg += fmt.Sprintf(
+			" you have %s left before you forfeit",
+			chess.FormatClock(game.Remaining(game.Turn, now)),
+		)
+	}
+
+	channel, err := s.UserChannelCreate(player.ID)
+	if err == nil {
+		_, err = s.ChannelMessageSend(channel.ID, msg)
+	}
+	if err != nil {
+		s.ChannelMessageSend(game.ChannelID, fmt.Sprintf("<@!%s>: %s", player.ID, msg))
+	}
+}
+
+//abortIfAbandoned aborts an untimed game nobody has moved in for too long
+func abortIfAbandoned(s *discordgo.Session, game *chess.Game, now time.Time) bool {
+	if game.TimeControl.Timed() || now.Sub(game.LastMoveTime()) < env.AbandonAfter {
+		return false
+	}
+
+	game.Result = chess.AbortedBy(chess.ReasonInactivity)
+
+	msg := fmt.Sprintf(
+		"Match between <@!%s>: %s and <@!%s>: %s Final State\n%s",
+		game.White.ID, game.White.Side.String(), game.Black.ID, game.Black.Side.String(),
+		resultMsg(game),
+	)
+	endGame(s, game.ChannelID, game, msg)
+	return true
+}
+
+//sweepGames ends games where the player to move ran out of time or
+//abandoned the game and reminds players who are taking a while
+func sweepGames(s *discordgo.Session) {
+	for range time.Tick(sweepInterval) {
+		games, err := dbIns.GetActiveGames()
+		if err != nil {
+			log.Printf("unable to get active games %v", err)
+			continue
+		}
+
+		now := time.Now().UTC()
+		for _, game := range games {
+			if endIfFlagged(s, game.ChannelID, game) || abortIfAbandoned(s, game, now) {
+				continue
+			}
+
+			//Saving first means a move made while reminding isn't lost
+			if reminderDue(game, now) {
+				game.Reminded = true
+				if err := dbIns.SaveGame(game); err != nil {
+					log.Printf("unable to save reminder for game %s %v", game.ID(), err)
+					continue
+				}
+				remindPlayer(s, game, now)
+			}
+		}
+	}
+}
+
 func infoCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
 	s.ChannelMessageSend(
 		m.ChannelID,
 		fmt.Sprintf(
 			"<@!%s>: Here is some info\n"+
-				"* When you see comands and this `<@!(?P<target>\\d{18})>` you should enter @ somebody\n"+
-				"* There is NO rule checking it's up to you and your opponent to not be shit cunts once somebody is in check mate they should resgin\n"+
-				"* To Castle you need to use a seprate move command see help for more info\n"+
-				"* To En Passant you need to use a seprate command after moving see help for more info", m.Author.ID,
+				"* When you see comands and this `<@!?(?P<target>\\d+)>` you should enter @ somebody\n"+
+				"* Games start with `challenge` and only begin once the other player replies `accept`\n"+
+				"* Play the computer with `@chessbot challenge` add `easy`, `medium` or `hard` to pick how strong it is\n"+
+				"* Games with a time control like `challenge 5+3` or `challenge 3d` are lost when your clock runs out\n"+
+				"* In slow games you'll get a reminder when it's been your move for a while, untimed games nobody moves in are aborted\n"+
+				"* You can have several games with the same player, add the game id after the mention like `@player 1a2b3c4d get`\n"+
+				"* Checkmate, stalemate, the fifty move rule, threefold repetition and insufficient material will end the game automatically\n"+
+				"* Moves can be two cells `move e2 e4`, UCI `move e2e4` or SAN `move Nf3`\n"+
+				"* To Castle move your king two cells towards the rook or use `move O-O` / `move O-O-O`\n"+
+				"* To promote a pawn add the piece it becomes to the end of the move like `move e7 e8=Q`\n"+
+				"* Offer a draw with `offer draw`, it stays open until you make your next move\n"+
+				"* Made a mistake? Use `request takeback` and your opponent can let you undo your last move\n"+
+				"* After a game use `analyse` to see where it went wrong\n"+
+				"* Games between players started with `challenge` are rated, check with `rating` or `rating @player`\n"+
+				"* See who's best with `leaderboard` or `leaderboard wins` and how you play with `stats` or `stats @player`\n"+
+				"* Look back at finished games with `history` or `history @player` and `replay` one by its archive id\n"+
+				"* Stuck? `hint` will DM you a suggested move, servers can turn them off for rated games with `rated hints off`\n"+
+				"* To En Passant move your pawn diagonally onto the cell the other pawn skipped over", m.Author.ID,
 		),
 	)
 }
@@ -180,8 +559,68 @@ func codeInfoCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
 	)
 }
 
-func startGameCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
-	matches := startGameRe.FindAllStringSubmatch(strings.ToLower(m.Content), -1)
+func listGamesCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
+	games, err := dbIns.GetPlayerGames(m.GuildID, m.Author.ID)
+	if err != nil {
+		printMissingGame(s, m, err)
+		return
+	}
+
+	if len(games) == 0 {
+		s.ChannelMessageSend(
+			m.ChannelID,
+			fmt.Sprintf("<@!%s>: You have no games going start one with `challenge`", m.Author.ID),
+		)
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<@!%s>: Your games", m.Author.ID)
+	for _, game := range games {
+		fmt.Fprintf(
+			&b, "\n`%s` against <@!%s> playing %s, %d moves, %s to move",
+			game.ID(), game.GetOpponent(m.Author.ID).ID, game.GetPlayer(m.Author.ID).Side,
+			len(game.Moves), game.Turn,
+		)
+	}
+
+	s.ChannelMessageSend(m.ChannelID, b.String())
+}
+
+//parseSideChoice reads the side a challenger asked for
+func parseSideChoice(str string) chess.SideType {
+	switch str {
+	case "white":
+		return chess.SideWhite
+	case "black":
+		return chess.SideBlack
+	}
+
+	return chess.SideEmpty
+}
+
+//sideChoiceStr describes the side a challenger asked for
+func sideChoiceStr(side chess.SideType) string {
+	if side == chess.SideEmpty {
+		return "a random side"
+	}
+
+	return side.String()
+}
+
+func challengeCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
+	matches := challengeRe.FindAllStringSubmatch(strings.ToLower(m.Content), -1)
+
+	if matches == nil {
+		s.ChannelMessageSend(
+			m.ChannelID,
+			fmt.Sprintf(
+				"<@!%s>: Invalid challenge try `challenge`, `challenge white 5+3` or `challenge random 3d`",
+				m.Author.ID,
+			),
+		)
+		return
+	}
 
 	target := matches[0][1]
 
@@ -195,72 +634,421 @@ func startGameCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
 		return
 	}
 
-	_, err := getGame(m, target)
-	if err == nil {
+	if _, err := dbIns.GetChallenge(chess.ChallengeID(m.GuildID, target, m.Author.ID)); err == nil {
 		s.ChannelMessageSend(
 			m.ChannelID,
 			fmt.Sprintf(
-				"<@!%s>: You already have a game going with that player", m.Author.ID,
+				"<@!%s>: That player has already challenged you reply with `@%s accept`",
+				m.Author.ID, username(s, target),
 			),
 		)
 		return
-
 	}
 
-	var white, black string
-	if rand.Float32() > 0.5 {
-		white = m.Author.ID
-		black = target
-	} else {
-		black = m.Author.ID
-		white = target
+	timeControl, err := chess.ParseTimeControl(matches[0][3])
+	if err != nil {
+		s.ChannelMessageSend(
+			m.ChannelID,
+			fmt.Sprintf("<@!%s>: %v", m.Author.ID, err),
+		)
+		return
 	}
 
 	var whiteColor, blackColor color.RGBA
-	if matches[0][3] != "" {
-		whiteColor, _ = colorx.ParseHexColor(matches[0][3])
-		blackColor, _ = colorx.ParseHexColor(matches[0][4])
+	if matches[0][5] != "" {
+		whiteColor, _ = colorx.ParseHexColor(matches[0][5])
+		blackColor, _ = colorx.ParseHexColor(matches[0][6])
 	} else {
 		whiteColor = color.RGBA{255, 255, 255, 255}
 		blackColor = color.RGBA{0, 0, 0, 255}
 	}
 
-	game := chess.CreateGame(white, black, m.GuildID, whiteColor, blackColor)
-	dbIns.SaveGame(&game)
+	challenge := chess.Challenge{
+		Challenger:  m.Author.ID,
+		Target:      target,
+		GuildID:     m.GuildID,
+		Side:        parseSideChoice(matches[0][2]),
+		TimeControl: timeControl,
+		WhiteColor:  whiteColor,
+		BlackColor:  blackColor,
+		Expires:     time.Now().UTC().Add(challengeExpiry),
+	}
 
-	msg := fmt.Sprintf(
-		"New Match Between <@!%s>: %s and <@!%s>: %s",
-		game.White.ID, game.White.Side.String(), game.Black.ID, game.Black.Side.String(),
-	)
-	sendGame(s, m.ChannelID, msg, &game)
-}
+	//The computer accepts straight away
+	if target == s.State.User.ID {
+		challenge.Computer, err = chess.ParseDifficulty(matches[0][4])
+		if err != nil {
+			s.ChannelMessageSend(
+				m.ChannelID,
+				fmt.Sprintf("<@!%s>: %v", m.Author.ID, err),
+			)
+			return
+		}
+
+		startChallengedGame(s, m.ChannelID, &challenge)
+		return
+	}
+
+	if err := dbIns.SaveChallenge(&challenge); err != nil {
+		s.ChannelMessageSend(
+			m.ChannelID,
+			fmt.Sprintf("<@!%s>: error saving challenge %v", m.Author.ID, err),
+		)
+		return
+	}
 
-func printMissingGame(s *discordgo.Session, m *discordgo.MessageCreate) {
 	s.ChannelMessageSend(
 		m.ChannelID,
-		fmt.Sprintf("<@!%s>: error getting game, game doesn't exist", m.Author.ID),
+		fmt.Sprintf(
+			"<@!%s>: <@!%s> challenges you to a %s game playing %s\n"+
+				"Reply with `@%s accept` or `@%s decline` within %v",
+			target, m.Author.ID, timeControl, sideChoiceStr(challenge.Side),
+			username(s, m.Author.ID), username(s, m.Author.ID), challengeExpiry,
+		),
 	)
 }
 
-func getGame(m *discordgo.MessageCreate, target string) (*chess.Game, error) {
-	return dbIns.GetGame(chess.GameID(m.GuildID, m.Author.ID, target))
-}
+//getChallenge gets a challenge from the challenger to the target
+func getChallenge(
+	s *discordgo.Session, m *discordgo.MessageCreate, challenger, target string,
+) (*chess.Challenge, error) {
+	challenge, err := dbIns.GetChallenge(chess.ChallengeID(m.GuildID, challenger, target))
+	if err != nil {
+		s.ChannelMessageSend(
+			m.ChannelID,
+			fmt.Sprintf("<@!%s>: error getting challenge, it doesn't exist or has expired", m.Author.ID),
+		)
+		return nil, err
+	}
 
-func rgbaToString(color color.RGBA) string {
-	return fmt.Sprintf("#%x%x%x", color.R, color.G, color.G)
+	return challenge, nil
 }
 
-func getGameCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
-	matches := getGameRe.FindAllStringSubmatch(strings.ToLower(m.Content), -1)
-
-	target := matches[0][1]
-
-	game, err := getGame(m, target)
+//removeChallenge deletes the challenge returning false if it couldn't or
+//someone else already answered it
+func removeChallenge(s *discordgo.Session, m *discordgo.MessageCreate, challenge *chess.Challenge) bool {
+	removed, err := dbIns.DeleteChallenge(challenge)
 	if err != nil {
-		printMissingGame(s, m)
-		return
-	}
-
+		s.ChannelMessageSend(
+			m.ChannelID,
+			fmt.Sprintf("<@!%s>: error removing challenge %v", m.Author.ID, err),
+		)
+		return false
+	}
+
+	if !removed {
+		s.ChannelMessageSend(
+			m.ChannelID,
+			fmt.Sprintf("<@!%s>: the challenge has already been answered", m.Author.ID),
+		)
+	}
+
+	return removed
+}
+
+func acceptChallengeCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
+	matches := acceptChallengeRe.FindAllStringSubmatch(strings.ToLower(m.Content), -1)
+
+	challenger := matches[0][1]
+
+	challenge, err := getChallenge(s, m, challenger, m.Author.ID)
+	if err != nil {
+		return
+	}
+
+	//Only whoever removed the challenge starts the game
+	if !removeChallenge(s, m, challenge) {
+		return
+	}
+
+	startChallengedGame(s, m.ChannelID, challenge)
+}
+
+//startChallengedGame creates the game for an accepted challenge
+func startChallengedGame(s *discordgo.Session, channelID string, challenge *chess.Challenge) {
+	randomSide := chess.SideWhite
+	if rand.Float32() > 0.5 {
+		randomSide = chess.SideBlack
+	}
+
+	game, err := challenge.CreateGame(randomSide)
+	if err != nil {
+		s.ChannelMessageSend(
+			channelID,
+			fmt.Sprintf("<@!%s>: Unable to start game %v", challenge.Challenger, err),
+		)
+		return
+	}
+	game.ChannelID = channelID
+	if !saveGame(s, channelID, challenge.Challenger, &game) {
+		return
+	}
+
+	msg := fmt.Sprintf(
+		"New Match Between <@!%s>: %s and <@!%s>: %s Time Control: %s",
+		game.White.ID, game.White.Side.String(), game.Black.ID, game.Black.Side.String(),
+		game.TimeControl,
+	)
+	sendGame(s, channelID, msg, &game)
+
+	if game.ComputerToMove() {
+		playComputerMove(s, channelID, &game)
+	}
+}
+
+func declineChallengeCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
+	matches := declineChallengeRe.FindAllStringSubmatch(strings.ToLower(m.Content), -1)
+
+	challenger := matches[0][1]
+
+	challenge, err := getChallenge(s, m, challenger, m.Author.ID)
+	if err != nil {
+		return
+	}
+
+	if !removeChallenge(s, m, challenge) {
+		return
+	}
+
+	s.ChannelMessageSend(
+		m.ChannelID,
+		fmt.Sprintf("<@!%s>: <@!%s> declined your challenge", challenger, m.Author.ID),
+	)
+}
+
+func cancelChallengeCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
+	matches := cancelChallengeRe.FindAllStringSubmatch(strings.ToLower(m.Content), -1)
+
+	target := matches[0][1]
+
+	challenge, err := getChallenge(s, m, m.Author.ID, target)
+	if err != nil {
+		return
+	}
+
+	if !removeChallenge(s, m, challenge) {
+		return
+	}
+
+	s.ChannelMessageSend(
+		m.ChannelID,
+		fmt.Sprintf("<@!%s>: Challenge to <@!%s> cancelled", m.Author.ID, target),
+	)
+}
+
+//downloadAttachment reads the text of a file attached to a message
+func downloadAttachment(url string) (string, error) {
+	resp, err := attachmentClient.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("downloading attachment returned %s", resp.Status)
+	}
+
+	//Read one byte past the limit to tell a full file from a cut off one
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxAttachmentSize+1))
+	if err != nil {
+		return "", err
+	}
+
+	if len(body) > maxAttachmentSize {
+		return "", fmt.Errorf("attachment is larger than %d bytes", maxAttachmentSize)
+	}
+
+	return string(body), nil
+}
+
+func loadPGNCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
+	matches := loadPGNRe.FindAllStringSubmatch(m.Content, -1)
+
+	if matches == nil {
+		s.ChannelMessageSend(
+			m.ChannelID,
+			fmt.Sprintf("<@!%s>: Invalid load command mention white then black", m.Author.ID),
+		)
+		return
+	}
+
+	white := matches[0][1]
+	black := matches[0][2]
+	pgn := matches[0][3]
+
+	if white == black {
+		s.ChannelMessageSend(
+			m.ChannelID,
+			fmt.Sprintf(
+				"<@!%s>: You cannot play with yourself god is watching", m.Author.ID,
+			),
+		)
+		return
+	}
+
+	if m.Author.ID != white && m.Author.ID != black {
+		s.ChannelMessageSend(
+			m.ChannelID,
+			fmt.Sprintf("<@!%s>: You can only load a game you are playing in", m.Author.ID),
+		)
+		return
+	}
+
+	if len(m.Attachments) > 0 {
+		var err error
+		pgn, err = downloadAttachment(m.Attachments[0].URL)
+		if err != nil {
+			s.ChannelMessageSend(
+				m.ChannelID,
+				fmt.Sprintf("<@!%s>: error reading attached PGN %v", m.Author.ID, err),
+			)
+			return
+		}
+	}
+
+	if strings.TrimSpace(pgn) == "" {
+		s.ChannelMessageSend(
+			m.ChannelID,
+			fmt.Sprintf("<@!%s>: Paste a PGN after the command or attach a .pgn file", m.Author.ID),
+		)
+		return
+	}
+
+	whiteColor, blackColor := color.RGBA{255, 255, 255, 255}, color.RGBA{0, 0, 0, 255}
+	game, err := chess.CreateGameFromPGN(white, black, m.GuildID, whiteColor, blackColor, pgn)
+	if err != nil {
+		s.ChannelMessageSend(
+			m.ChannelID,
+			fmt.Sprintf("<@!%s>: Unable to load PGN %v", m.Author.ID, err),
+		)
+		return
+	}
+
+	//Finished games are only replayed
+	if game.Over() {
+		s.ChannelMessageSendComplex(
+			m.ChannelID,
+			&discordgo.MessageSend{
+				Content: fmt.Sprintf(
+					"Loaded Match Between <@!%s>: %s and <@!%s>: %s Final State %s",
+					game.White.ID, game.White.Side.String(), game.Black.ID, game.Black.Side.String(),
+					game.ResultString(),
+				),
+				Files: []*discordgo.File{{
+					Name: fmt.Sprintf("%s.gif", game.ID()), ContentType: "gif",
+					Reader: game.CreateGif(),
+				}},
+			},
+		)
+		return
+	}
+
+	//Unfinished games are played on so the other player has to accept
+	challenge := chess.Challenge{
+		Challenger: m.Author.ID,
+		Target:     white,
+		GuildID:    m.GuildID,
+		Side:       chess.SideBlack,
+		WhiteColor: whiteColor,
+		BlackColor: blackColor,
+		Expires:    time.Now().UTC().Add(challengeExpiry),
+		PGN:        pgn,
+	}
+	if m.Author.ID == white {
+		challenge.Target, challenge.Side = black, chess.SideWhite
+	}
+
+	if err := dbIns.SaveChallenge(&challenge); err != nil {
+		s.ChannelMessageSend(
+			m.ChannelID,
+			fmt.Sprintf("<@!%s>: error saving challenge %v", m.Author.ID, err),
+		)
+		return
+	}
+
+	s.ChannelMessageSend(
+		m.ChannelID,
+		fmt.Sprintf(
+			"<@!%s>: <@!%s> challenges you to play on from a loaded game playing %s\n"+
+				"Reply with `@%s accept` or `@%s decline` within %v",
+			challenge.Target, m.Author.ID, sideChoiceStr(challenge.Side),
+			username(s, m.Author.ID), username(s, m.Author.ID), challengeExpiry,
+		),
+	)
+}
+
+//saveGame saves a game telling the player if it couldn't be saved
+func saveGame(s *discordgo.Session, channelID, playerID string, game *chess.Game) bool {
+	err := dbIns.SaveGame(game)
+	if err == db.ErrGameChanged {
+		s.ChannelMessageSend(channelID, fmt.Sprintf("<@!%s>: %v", playerID, err))
+	} else if err != nil {
+		s.ChannelMessageSend(
+			channelID,
+			fmt.Sprintf("<@!%s>: error saving game `%s` %v", playerID, game.ID(), err),
+		)
+	}
+
+	return err == nil
+}
+
+func printMissingGame(s *discordgo.Session, m *discordgo.MessageCreate, err error) {
+	s.ChannelMessageSend(
+		m.ChannelID,
+		fmt.Sprintf("<@!%s>: error getting game, %v", m.Author.ID, err),
+	)
+}
+
+//getGame finds the game between the author and the target, the game id
+//picks one when they have more than one game going
+func getGame(m *discordgo.MessageCreate, target, gameID string) (*chess.Game, error) {
+	if gameID != "" {
+		game, err := dbIns.GetGame(gameID)
+		if err != nil || game.PairID() != chess.PairID(m.GuildID, m.Author.ID, target) {
+			return nil, errMissingGame
+		}
+
+		return game, nil
+	}
+
+	games, err := dbIns.GetPairGames(m.GuildID, m.Author.ID, target)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(games) {
+	case 0:
+		return nil, errMissingGame
+	case 1:
+		return games[0], nil
+	}
+
+	ids := make([]string, len(games))
+	for i, game := range games {
+		ids[i] = fmt.Sprintf("`%s`", game.ID())
+	}
+
+	return nil, errors.Errorf(
+		"you have %d games with that player put the game id after the mention (%s)",
+		len(games), strings.Join(ids, ", "),
+	)
+}
+
+func rgbaToString(color color.RGBA) string {
+	return fmt.Sprintf("#%x%x%x", color.R, color.G, color.G)
+}
+
+func getGameCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
+	matches := getGameRe.FindAllStringSubmatch(strings.ToLower(m.Content), -1)
+
+	target := matches[0][1]
+
+	game, err := getGame(m, target, matches[0][2])
+	if err != nil {
+		printMissingGame(s, m, err)
+		return
+	}
+
 	msg := fmt.Sprintf(
 		"Match between <@!%s>: %s and <@!%s>: %s\n"+
 			"White Color: %s Black Color: %s",
@@ -270,278 +1058,1173 @@ func getGameCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
 	sendGame(s, m.ChannelID, msg, game)
 }
 
-func getMovesCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
-	matches := getMovesRe.FindAllStringSubmatch(strings.ToLower(m.Content), -1)
+func getMovesCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
+	matches := getMovesRe.FindAllStringSubmatch(strings.ToLower(m.Content), -1)
+
+	target := matches[0][1]
+
+	game, err := getGame(m, target, matches[0][2])
+	if err != nil {
+		printMissingGame(s, m, err)
+		return
+	}
+
+	msg := fmt.Sprintf(
+		"Match between <@!%s>: %s and <@!%s>: %s all moves:\n%v",
+		game.White.ID, game.White.Side.String(), game.Black.ID,
+		game.Black.Side.String(), game.AlgebraicNotation(),
+	)
+	s.ChannelMessageSendComplex(
+		m.ChannelID,
+		&discordgo.MessageSend{
+			Content: msg,
+			Files: []*discordgo.File{{
+				Name: fmt.Sprintf("%s.gif", game.ID()), ContentType: "gif",
+				Reader: game.CreateGif(),
+			}},
+		},
+	)
+}
+
+func getFENCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
+	matches := getFENRe.FindAllStringSubmatch(strings.ToLower(m.Content), -1)
+
+	target := matches[0][1]
+
+	game, err := getGame(m, target, matches[0][2])
+	if err != nil {
+		printMissingGame(s, m, err)
+		return
+	}
+
+	s.ChannelMessageSend(
+		m.ChannelID,
+		fmt.Sprintf("<@!%s>: `%s`", m.Author.ID, game.FEN()),
+	)
+}
+
+//username returns the discord username for a user id or the id if it can't be found
+func username(s *discordgo.Session, id string) string {
+	user, err := s.User(id)
+	if err != nil {
+		return id
+	}
+
+	return user.Username
+}
+
+//pgnHeader resolves the names used in a games PGN tags
+func pgnHeader(s *discordgo.Session, game *chess.Game) chess.PGNHeader {
+	site := game.GuildID
+	if guild, err := s.State.Guild(game.GuildID); err == nil {
+		site = guild.Name
+	}
+
+	return chess.PGNHeader{
+		Event: "Chessbot game",
+		Site:  fmt.Sprintf("Discord %s", site),
+		White: username(s, game.White.ID),
+		Black: username(s, game.Black.ID),
+	}
+}
+
+func getPGNCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
+	matches := getPGNRe.FindAllStringSubmatch(strings.ToLower(m.Content), -1)
+
+	target := matches[0][1]
+
+	game, err := getGame(m, target, matches[0][2])
+	if err != nil {
+		printMissingGame(s, m, err)
+		return
+	}
+
+	msg := fmt.Sprintf(
+		"Match between <@!%s>: %s and <@!%s>: %s PGN",
+		game.White.ID, game.White.Side.String(), game.Black.ID, game.Black.Side.String(),
+	)
+	s.ChannelMessageSendComplex(
+		m.ChannelID,
+		&discordgo.MessageSend{
+			Content: msg,
+			Files: []*discordgo.File{{
+				Name: fmt.Sprintf("%s.pgn", game.ID()), ContentType: "application/vnd.chess-pgn",
+				Reader: strings.NewReader(game.PGN(pgnHeader(s, game))),
+			}},
+		},
+	)
+}
+
+//getFinishedGame gets an archived game between the author and target,
+//the last one they finished when there is no game id
+func getFinishedGame(m *discordgo.MessageCreate, target, gameID string) (*chess.Game, error) {
+	if gameID == "" {
+		game, err := dbIns.GetLastArchivedGame(m.GuildID, m.Author.ID, target)
+		if err != nil {
+			return nil, errMissingGame
+		}
+
+		return game, nil
+	}
+
+	game, err := dbIns.GetArchivedGame(gameID)
+	if err != nil || game.PairID() != chess.PairID(m.GuildID, m.Author.ID, target) {
+		return nil, errMissingGame
+	}
+
+	return game, nil
+}
+
+//analyser the external engine when one is set up otherwise the built in
+//engine, close must be called when done
+func analyser() (chess.Analyser, func()) {
+	if env.UCIEnginePath != "" {
+		engine, err := uci.Start(uci.Options{Path: env.UCIEnginePath, Timeout: env.UCITimeout})
+		if err == nil {
+			return engine.Analyser(analyseMoveTime), func() { engine.Close() }
+		}
+		log.Printf("external engine failed using the built in engine %v", err)
+	}
+
+	return chess.DefaultAnalyser, func() {}
+}
+
+//judgementCounts shows how many inaccuracies, mistakes and blunders a side made
+func judgementCounts(analysis *chess.Analysis, side chess.SideType) string {
+	return fmt.Sprintf(
+		"%d inaccuracies, %d mistakes, %d blunders",
+		analysis.Count(side, chess.JudgementInaccuracy),
+		analysis.Count(side, chess.JudgementMistake),
+		analysis.Count(side, chess.JudgementBlunder),
+	)
+}
+
+func analyseCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
+	matches := analyseRe.FindAllStringSubmatch(strings.ToLower(m.Content), -1)
+
+	target := matches[0][1]
+
+	game, err := getFinishedGame(m, target, matches[0][2])
+	if err != nil {
+		s.ChannelMessageSend(
+			m.ChannelID,
+			fmt.Sprintf("<@!%s> you have no finished game with that player", m.Author.ID),
+		)
+		return
+	}
+
+	s.ChannelMessageSend(
+		m.ChannelID,
+		fmt.Sprintf("Analysing %d moves of game `%s` this may take a while", len(game.Moves), game.ID()),
+	)
+	s.ChannelTyping(m.ChannelID)
+
+	engine, done := analyser()
+	analysis, err := game.Analyse(engine)
+	done()
+	if err != nil {
+		s.ChannelMessageSend(
+			m.ChannelID,
+			fmt.Sprintf("error analysing game `%s`! %v", game.ID(), err),
+		)
+		return
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(
+		&msg, "Analysis of <@!%s> vs <@!%s> %s\nWhite: %s\nBlack: %s\n",
+		game.White.ID, game.Black.ID, game.Result,
+		judgementCounts(analysis, chess.SideWhite), judgementCounts(analysis, chess.SideBlack),
+	)
+	for _, mv := range analysis.Moves {
+		if mv.Judgement == chess.JudgementNone {
+			continue
+		}
+
+		line := fmt.Sprintf(
+			"%s%s %s (%s → %s) best was %s\n",
+			mv.Label(), mv.Judgement.Symbol(), mv.Judgement, mv.Before, mv.After, mv.BestSAN,
+		)
+		if msg.Len()+len(line) > maxMessageLength {
+			break
+		}
+		msg.WriteString(line)
+	}
+
+	s.ChannelMessageSendComplex(
+		m.ChannelID,
+		&discordgo.MessageSend{
+			Content: msg.String(),
+			Files: []*discordgo.File{
+				{
+					Name:   fmt.Sprintf("%s-analysis.png", game.ID()),
+					Reader: analysis.CreateGraph(),
+				},
+				{
+					Name:   fmt.Sprintf("%s-analysis.txt", game.ID()),
+					Reader: strings.NewReader(analysis.Annotated()),
+				},
+			},
+		},
+	)
+}
+
+func hintCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
+	matches := hintRe.FindAllStringSubmatch(strings.ToLower(m.Content), -1)
+
+	target := matches[0][1]
+
+	game, err := getGame(m, target, matches[0][2])
+	if err != nil {
+		printMissingGame(s, m, err)
+		return
+	}
+
+	if game.GetSidePlayer(game.Turn).ID != m.Author.ID {
+		s.ChannelMessageSend(
+			m.ChannelID,
+			fmt.Sprintf("<@!%s>: You can only get a hint on your move", m.Author.ID),
+		)
+		return
+	}
+
+	if game.Rated {
+		settings, err := dbIns.GetGuildSettings(m.GuildID)
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("error getting settings! %v", err))
+			return
+		}
+
+		if settings.NoRatedHints {
+			s.ChannelMessageSend(
+				m.ChannelID,
+				fmt.Sprintf("<@!%s>: Hints are turned off for rated games in this server", m.Author.ID),
+			)
+			return
+		}
+	}
+
+	mv, err := computerMove(game, chess.DifficultyHard)
+	if err != nil {
+		s.ChannelMessageSend(
+			m.ChannelID,
+			fmt.Sprintf("error finding a hint! %v", err),
+		)
+		return
+	}
+
+	//Sent by DM so the opponent doesn't see it
+	channel, err := s.UserChannelCreate(m.Author.ID)
+	if err == nil {
+		_, err = s.ChannelMessageSendComplex(
+			channel.ID,
+			&discordgo.MessageSend{
+				Content: fmt.Sprintf(
+					"Hint for your game against <@!%s> (`%s`): try **%s**",
+					target, game.ID(), game.MoveSAN(mv),
+				),
+				Files: []*discordgo.File{{
+					Name:   fmt.Sprintf("%s-hint.png", game.ID()),
+					Reader: game.CreateHintImage(mv),
+				}},
+			},
+		)
+	}
+	if err != nil {
+		s.ChannelMessageSend(
+			m.ChannelID,
+			fmt.Sprintf("<@!%s>: Unable to DM you a hint, allow direct messages from server members", m.Author.ID),
+		)
+		return
+	}
+
+	s.ChannelMessageSend(
+		m.ChannelID,
+		fmt.Sprintf("<@!%s>: Your hint has been sent by DM", m.Author.ID),
+	)
+}
+
+func ratedHintsCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
+	matches := ratedHintsRe.FindAllStringSubmatch(strings.ToLower(m.Content), -1)
+
+	perms, err := s.UserChannelPermissions(m.Author.ID, m.ChannelID)
+	if err != nil || perms&discordgo.PermissionManageServer == 0 {
+		s.ChannelMessageSend(
+			m.ChannelID,
+			fmt.Sprintf("<@!%s>: You need the manage server permission to change that", m.Author.ID),
+		)
+		return
+	}
+
+	settings, err := dbIns.GetGuildSettings(m.GuildID)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("error getting settings! %v", err))
+		return
+	}
+
+	settings.NoRatedHints = matches[0][1] == "off"
+	if err := dbIns.SaveGuildSettings(m.GuildID, settings); err != nil {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("error saving settings! %v", err))
+		return
+	}
+
+	s.ChannelMessageSend(
+		m.ChannelID,
+		fmt.Sprintf("<@!%s>: Hints in rated games are now %s", m.Author.ID, matches[0][1]),
+	)
+}
+
+func moveCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
+	matches := moveRe.FindAllStringSubmatch(m.Content, -1)
+
+	if matches == nil {
+		s.ChannelMessageSend(
+			m.ChannelID,
+			fmt.Sprintf(
+				"<@!%s> Invalid Move try `move e2 e4`, `move e2e4` or `move Nf3`",
+				m.Author.ID,
+			),
+		)
+		return
+	}
+
+	target := matches[0][1]
+	moveStr := matches[0][3]
+
+	game, err := getGame(m, target, matches[0][2])
+	if err != nil {
+		printMissingGame(s, m, err)
+		return
+	}
+
+	if endIfFlagged(s, m.ChannelID, game) {
+		return
+	}
+
+	//Check the turn first so SAN isn't read for the wrong side
+	if err := game.CanMove(m.Author.ID); err != nil {
+		s.ChannelMessageSend(
+			m.ChannelID,
+			fmt.Sprintf(
+				"<@!%s> Invalid Move %s",
+				m.Author.ID, invalidMoveReason(game, chess.Move{}, err),
+			),
+		)
+		return
+	}
+
+	mv, err := game.ParseMove(moveStr)
+	if err != nil {
+		s.ChannelMessageSend(
+			m.ChannelID,
+			fmt.Sprintf(
+				"<@!%s> Invalid Move %v try `move e2 e4`, `move e2e4` or `move Nf3`",
+				m.Author.ID, err,
+			),
+		)
+		return
+	}
+
+	playMove(s, m.ChannelID, m.Author.ID, game, mv)
+}
+
+//invalidMoveReason explains why a move was rejected
+func invalidMoveReason(game *chess.Game, mv chess.Move, err error) string {
+	switch err {
+	case chess.ErrNotYourTurn:
+		return fmt.Sprintf("it's <@!%s>'s turn", game.GetSidePlayer(game.Turn).ID)
+	case chess.ErrNotYourPiece:
+		return fmt.Sprintf("the piece on %s isn't yours", mv.From.String())
+	case chess.ErrNoPieceAtSource:
+		return fmt.Sprintf("there is no piece on %s", mv.From.String())
+	}
+
+	return err.Error()
+}
+
+//playMove validates and makes a move then saves and shows the game, the
+//computer replies straight away in games against the bot
+func playMove(s *discordgo.Session, channelID, playerID string, game *chess.Game, mv chess.Move) {
+	if err := game.ValidMove(playerID, mv); err != nil {
+		s.ChannelMessageSend(
+			channelID,
+			fmt.Sprintf(
+				"<@!%s> Invalid Move %s",
+				playerID, invalidMoveReason(game, mv, err),
+			),
+		)
+		return
+	}
+
+	san := game.MoveSAN(mv)
+	game.MakeMove(mv)
+	game.ChannelID = channelID
+
+	msg := fmt.Sprintf(
+		"Match between <@!%s>: %s and <@!%s>: %s Move %s",
+		game.White.ID, game.White.Side.String(), game.Black.ID, game.Black.Side.String(), san,
+	)
+
+	if game.Over() {
+		endGame(s, channelID, game, msg+"\n"+resultMsg(game))
+		return
+	}
+
+	if !saveGame(s, channelID, playerID, game) {
+		return
+	}
+
+	sendGame(s, channelID, msg, game)
+
+	if game.ComputerToMove() {
+		playComputerMove(s, channelID, game)
+	}
+}
+
+//externalMove asks the configured UCI engine for a move
+func externalMove(game *chess.Game) (chess.Move, error) {
+	engine, err := uci.Start(uci.Options{Path: env.UCIEnginePath, Timeout: env.UCITimeout})
+	if err != nil {
+		return chess.Move{}, err
+	}
+	defer engine.Close()
+
+	mv, _, err := engine.BestMove(game, env.UCIMoveTime)
+	return mv, err
+}
+
+//computerMove picks the computer's move, hard games use the external
+//engine when one is set up
+func computerMove(game *chess.Game, difficulty chess.Difficulty) (chess.Move, error) {
+	if difficulty == chess.DifficultyHard && env.UCIEnginePath != "" {
+		mv, err := externalMove(game)
+		if err == nil {
+			return mv, nil
+		}
+		log.Printf("external engine failed using the built in engine %v", err)
+	}
+
+	return game.BestMove(difficulty)
+}
+
+//playComputerMove has the engine make its move
+func playComputerMove(s *discordgo.Session, channelID string, game *chess.Game) {
+	s.ChannelTyping(channelID)
+
+	computer := game.GetSidePlayer(game.Turn)
+	mv, err := computerMove(game, computer.Computer)
+	if err != nil {
+		s.ChannelMessageSend(
+			channelID,
+			fmt.Sprintf("error picking the computer's move! %v", err),
+		)
+		return
+	}
+
+	playMove(s, channelID, computer.ID, game, mv)
+}
+
+func resginCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
+	matches := resginRe.FindAllStringSubmatch(strings.ToLower(m.Content), -1)
+
+	target := matches[0][1]
+
+	game, err := getGame(m, target, matches[0][2])
+	if err != nil {
+		printMissingGame(s, m, err)
+		return
+	}
+
+	if err := game.Resign(m.Author.ID); err != nil {
+		s.ChannelMessageSend(
+			m.ChannelID,
+			fmt.Sprintf("<@!%s>: Unable to resign %v", m.Author.ID, err),
+		)
+		return
+	}
+
+	msg := fmt.Sprintf(
+		"Match between <@!%s>: %s and <@!%s>: %s Final State\n"+
+			"🎉Winner🎉 <@!%s>",
+		game.White.ID, game.White.Side.String(), game.Black.ID, game.Black.Side.String(),
+		game.GetOpponent(m.Author.ID).ID,
+	)
+	endGame(s, m.ChannelID, game, msg)
+}
+
+func offerDrawCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
+	matches := offerDrawRe.FindAllStringSubmatch(strings.ToLower(m.Content), -1)
+
+	target := matches[0][1]
+
+	game, err := getGame(m, target, matches[0][2])
+	if err != nil {
+		printMissingGame(s, m, err)
+		return
+	}
+
+	if err := game.OfferDraw(m.Author.ID); err != nil {
+		s.ChannelMessageSend(
+			m.ChannelID,
+			fmt.Sprintf("<@!%s>: Unable to offer a draw %v", m.Author.ID, err),
+		)
+		return
+	}
+
+	if !saveGame(s, m.ChannelID, m.Author.ID, game) {
+		return
+	}
+
+	s.ChannelMessageSend(
+		m.ChannelID,
+		fmt.Sprintf(
+			"<@!%s>: <@!%s> offers a draw, reply with `accept draw` or `decline draw`",
+			game.GetOpponent(m.Author.ID).ID, m.Author.ID,
+		),
+	)
+}
+
+func acceptDrawCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
+	matches := acceptDrawRe.FindAllStringSubmatch(strings.ToLower(m.Content), -1)
+
+	target := matches[0][1]
+
+	game, err := getGame(m, target, matches[0][2])
+	if err != nil {
+		printMissingGame(s, m, err)
+		return
+	}
+
+	if err := game.AcceptDraw(m.Author.ID); err != nil {
+		s.ChannelMessageSend(
+			m.ChannelID,
+			fmt.Sprintf("<@!%s>: Unable to accept draw %v", m.Author.ID, err),
+		)
+		return
+	}
+
+	msg := fmt.Sprintf(
+		"Match between <@!%s>: %s and <@!%s>: %s Final State\n%s",
+		game.White.ID, game.White.Side.String(), game.Black.ID, game.Black.Side.String(),
+		resultMsg(game),
+	)
+	endGame(s, m.ChannelID, game, msg)
+}
+
+func declineDrawCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
+	matches := declineDrawRe.FindAllStringSubmatch(strings.ToLower(m.Content), -1)
+
+	target := matches[0][1]
+
+	game, err := getGame(m, target, matches[0][2])
+	if err != nil {
+		printMissingGame(s, m, err)
+		return
+	}
+
+	if err := game.DeclineDraw(m.Author.ID); err != nil {
+		s.ChannelMessageSend(
+			m.ChannelID,
+			fmt.Sprintf("<@!%s>: Unable to decline draw %v", m.Author.ID, err),
+		)
+		return
+	}
+
+	if !saveGame(s, m.ChannelID, m.Author.ID, game) {
+		return
+	}
+
+	s.ChannelMessageSend(
+		m.ChannelID,
+		fmt.Sprintf(
+			"<@!%s>: <@!%s> declined your draw offer", game.GetOpponent(m.Author.ID).ID, m.Author.ID,
+		),
+	)
+}
+
+func requestTakebackCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
+	matches := requestTakebackRe.FindAllStringSubmatch(strings.ToLower(m.Content), -1)
 
 	target := matches[0][1]
 
-	game, err := getGame(m, target)
+	game, err := getGame(m, target, matches[0][2])
 	if err != nil {
-		printMissingGame(s, m)
+		printMissingGame(s, m, err)
 		return
 	}
 
-	msg := fmt.Sprintf(
-		"Match between <@!%s>: %s and <@!%s>: %s all moves:\n%v",
-		game.White.ID, game.White.Side.String(), game.Black.ID,
-		game.Black.Side.String(), game.AlgebraicNotation(),
-	)
-	s.ChannelMessageSendComplex(
+	if err := game.RequestTakeback(m.Author.ID); err != nil {
+		s.ChannelMessageSend(
+			m.ChannelID,
+			fmt.Sprintf("<@!%s>: Unable to request a takeback %v", m.Author.ID, err),
+		)
+		return
+	}
+
+	//The computer always lets you take a move back
+	if opponent := game.GetOpponent(m.Author.ID); opponent.Computer != chess.DifficultyNone {
+		game.AcceptTakeback(opponent.ID)
+		if !saveGame(s, m.ChannelID, m.Author.ID, game) {
+			return
+		}
+
+		msg := fmt.Sprintf(
+			"Match between <@!%s>: %s and <@!%s>: %s Move taken back <@!%s> to move",
+			game.White.ID, game.White.Side.String(), game.Black.ID, game.Black.Side.String(),
+			game.GetSidePlayer(game.Turn).ID,
+		)
+		sendGame(s, m.ChannelID, msg, game)
+		return
+	}
+
+	if !saveGame(s, m.ChannelID, m.Author.ID, game) {
+		return
+	}
+
+	s.ChannelMessageSend(
 		m.ChannelID,
-		&discordgo.MessageSend{
-			Content: msg,
-			Files: []*discordgo.File{{
-				Name: fmt.Sprintf("%s.gif", game.ID()), ContentType: "gif",
-				Reader: game.CreateGif(),
-			}},
-		},
+		fmt.Sprintf(
+			"<@!%s>: <@!%s> wants to take back their last move, reply with `accept takeback` or `reject takeback`",
+			game.GetOpponent(m.Author.ID).ID, m.Author.ID,
+		),
 	)
 }
 
-func moveCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
-	matches := moveRe.FindAllStringSubmatch(strings.ToLower(m.Content), -1)
+func acceptTakebackCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
+	matches := acceptTakebackRe.FindAllStringSubmatch(strings.ToLower(m.Content), -1)
 
-	if matches == nil {
+	target := matches[0][1]
+
+	game, err := getGame(m, target, matches[0][2])
+	if err != nil {
+		printMissingGame(s, m, err)
+		return
+	}
+
+	if err := game.AcceptTakeback(m.Author.ID); err != nil {
 		s.ChannelMessageSend(
 			m.ChannelID,
-			fmt.Sprintf(
-				"<@!%s> Invalid Move try using uppercase",
-				m.Author.ID,
-			),
+			fmt.Sprintf("<@!%s>: Unable to accept takeback %v", m.Author.ID, err),
 		)
 		return
 	}
 
+	if !saveGame(s, m.ChannelID, m.Author.ID, game) {
+		return
+	}
+
+	msg := fmt.Sprintf(
+		"Match between <@!%s>: %s and <@!%s>: %s Move taken back <@!%s> to move",
+		game.White.ID, game.White.Side.String(), game.Black.ID, game.Black.Side.String(),
+		game.GetSidePlayer(game.Turn).ID,
+	)
+	sendGame(s, m.ChannelID, msg, game)
+}
+
+func rejectTakebackCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
+	matches := rejectTakebackRe.FindAllStringSubmatch(strings.ToLower(m.Content), -1)
+
 	target := matches[0][1]
-	from := matches[0][2]
-	to := matches[0][3]
 
-	game, err := getGame(m, target)
+	game, err := getGame(m, target, matches[0][2])
 	if err != nil {
-		printMissingGame(s, m)
+		printMissingGame(s, m, err)
 		return
 	}
 
-	mv := chess.Move{
-		From: chess.StringToPostion(from),
-		To:   chess.StringToPostion(to),
-	}
-
-	if err := game.ValidMove(m.Author.ID, mv); err != nil {
+	if err := game.RejectTakeback(m.Author.ID); err != nil {
 		s.ChannelMessageSend(
 			m.ChannelID,
-			fmt.Sprintf(
-				"<@!%s> Invalid Move %s",
-				m.Author.ID, err,
-			),
+			fmt.Sprintf("<@!%s>: Unable to reject takeback %v", m.Author.ID, err),
 		)
 		return
 	}
 
-	game.MakeMove(mv)
+	if !saveGame(s, m.ChannelID, m.Author.ID, game) {
+		return
+	}
+
+	s.ChannelMessageSend(
+		m.ChannelID,
+		fmt.Sprintf(
+			"<@!%s>: <@!%s> rejected your takeback request", game.GetOpponent(m.Author.ID).ID, m.Author.ID,
+		),
+	)
+}
 
-	dbIns.SaveGame(game)
+//resultMsg describes how a game that is over ended
+func resultMsg(game *chess.Game) string {
+	switch game.Result.Outcome {
+	case chess.OutcomeDraw:
+		return fmt.Sprintf("The game is a draw by %s", game.Result.Reason)
+	case chess.OutcomeAborted:
+		return fmt.Sprintf("The game was aborted for %s", game.Result.Reason)
+	}
 
-	msg := fmt.Sprintf(
-		"Match between <@!%s>: %s and <@!%s>: %s Move %s to %s",
-		game.White.ID, game.White.Side.String(), game.Black.ID, game.Black.Side.String(), from, to,
+	return fmt.Sprintf(
+		"%s! 🎉Winner🎉 <@!%s>",
+		strings.Title(game.Result.Reason.String()), game.GetSidePlayer(game.Result.Winner()).ID,
 	)
-	sendGame(s, m.ChannelID, msg, game)
 }
 
-func castlingCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
-	matches := castlingRe.FindAllStringSubmatch(strings.ToLower(m.Content), -1)
+//updateRatings rates a finished game in its guild and globally, returns how
+//the guild ratings changed
+func updateRatings(game *chess.Game) (string, error) {
+	if !game.Rated || !game.Over() || game.Result.Outcome == chess.OutcomeAborted {
+		return "", nil
+	}
+
+	var score float64 = rating.Draw
+	switch game.Result.Outcome {
+	case chess.OutcomeWhiteWins:
+		score = rating.Win
+	case chess.OutcomeBlackWins:
+		score = rating.Loss
+	}
 
-	if matches == nil {
-		s.ChannelMessageSend(
-			m.ChannelID,
-			fmt.Sprintf(
-				"<@!%s> Invalid castling move try using uppercase",
-				m.Author.ID,
-			),
+	now := time.Now().UTC()
+
+	msg := ""
+	for _, scope := range []string{game.GuildID, db.GlobalRatings} {
+		newWhite, newBlack, err := dbIns.UpdateRatings(
+			scope, game.White.ID, game.Black.ID,
+			func(white, black rating.Rating) (rating.Rating, rating.Rating) {
+				white, black = white.Decay(now), black.Decay(now)
+				return white.Update(now, rating.Result{Opponent: black, Score: score}),
+					black.Update(now, rating.Result{Opponent: white, Score: 1 - score})
+			},
 		)
-		return
+		if err != nil {
+			return "", err
+		}
+
+		if scope == game.GuildID {
+			msg = fmt.Sprintf(
+				"Ratings: <@!%s> %s (%+.0f) <@!%s> %s (%+.0f)",
+				game.White.ID, newWhite, newWhite.Change, game.Black.ID, newBlack, newBlack.Change,
+			)
+		}
+	}
+
+	return msg, nil
+}
+
+//ratingMsg describes a rating
+func ratingMsg(r rating.Rating) string {
+	if r.Games == 0 {
+		return "no rated games yet"
 	}
 
+	return fmt.Sprintf(
+		"%s ±%.0f (%+.0f last game, %d games)", r, r.Deviation, r.Change, r.Games,
+	)
+}
+
+func ratingCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
+	matches := ratingRe.FindAllStringSubmatch(strings.ToLower(m.Content), -1)
+
 	target := matches[0][1]
-	aFrom := matches[0][2]
-	aTo := matches[0][3]
+	if target == "" {
+		target = m.Author.ID
+	}
 
-	bFrom := matches[0][4]
-	bTo := matches[0][5]
+	now := time.Now().UTC()
 
-	game, err := getGame(m, target)
+	guild, err := dbIns.GetRating(m.GuildID, target)
 	if err != nil {
-		printMissingGame(s, m)
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("error getting rating! %v", err))
 		return
 	}
 
-	if game.Turn != game.GetPlayer(m.Author.ID).Side {
-		s.ChannelMessageSend(
-			m.ChannelID,
-			fmt.Sprintf(
-				"<@!%s> Invalid castling move it's not your turn",
-				m.Author.ID,
-			),
-		)
+	global, err := dbIns.GetRating(db.GlobalRatings, target)
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("error getting rating! %v", err))
 		return
 	}
 
-	game.MakeMove(chess.Move{
-		From: chess.StringToPostion(aFrom),
-		To:   chess.StringToPostion(aTo),
-	})
-	game.MakeMove(chess.Move{
-		From: chess.StringToPostion(bFrom),
-		To:   chess.StringToPostion(bTo),
-	})
+	s.ChannelMessageSend(
+		m.ChannelID,
+		fmt.Sprintf(
+			"<@!%s>'s rating in this server: %s\nGlobal rating: %s",
+			target, ratingMsg(guild.Decay(now)), ratingMsg(global.Decay(now)),
+		),
+	)
+}
 
-	game.Turn = game.GetOpponent(m.Author.ID).Side
+func leaderboardCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
+	matches := leaderboardRe.FindAllStringSubmatch(strings.ToLower(m.Content), -1)
 
-	dbIns.SaveGame(game)
+	byWins := matches[0][1] == "wins"
 
-	msg := fmt.Sprintf(
-		"Match between <@!%s>: %s and <@!%s>: %s castling move",
-		game.White.ID, game.White.Side.String(), game.Black.ID, game.Black.Side.String(),
-	)
-	sendGame(s, m.ChannelID, msg, game)
+	var entries []db.LeaderboardEntry
+	var err error
+	if byWins {
+		entries, err = dbIns.GetWinsLeaderboard(m.GuildID, leaderboardSize)
+	} else {
+		entries, err = dbIns.GetRatingLeaderboard(m.GuildID, leaderboardSize)
+	}
+	if err != nil {
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("error getting leaderboard! %v", err))
+		return
+	}
+
+	if len(entries) == 0 {
+		s.ChannelMessageSend(m.ChannelID, "Nobody has finished a rated game in this server yet")
+		return
+	}
+
+	now := time.Now().UTC()
+
+	var msg strings.Builder
+	if byWins {
+		msg.WriteString("Most wins in this server\n")
+	} else {
+		msg.WriteString("Highest rated in this server\n")
+	}
+	for i, entry := range entries {
+		fmt.Fprintf(&msg, "%d. %s ", i+1, username(s, entry.PlayerID))
+
+		if byWins {
+			fmt.Fprintf(&msg, "%.0f wins\n", entry.Score)
+			continue
+		}
+
+		r, err := dbIns.GetRating(m.GuildID, entry.PlayerID)
+		if err != nil {
+			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("error getting rating! %v", err))
+			return
+		}
+		fmt.Fprintf(&msg, "%s\n", ratingMsg(r.Decay(now)))
+	}
+
+	s.ChannelMessageSend(m.ChannelID, msg.String())
 }
 
-func enPassantCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
-	matches := enPassantRe.FindAllStringSubmatch(strings.ToLower(m.Content), -1)
+//recordMsg shows wins, losses and draws
+func recordMsg(stats *chess.PlayerStats) string {
+	return fmt.Sprintf("%d wins, %d losses, %d draws", stats.Wins, stats.Losses, stats.Draws)
+}
 
-	if matches == nil {
-		s.ChannelMessageSend(
-			m.ChannelID,
-			fmt.Sprintf(
-				"<@!%s> Invalid Move try using uppercase",
-				m.Author.ID,
-			),
-		)
-		return
+func statsCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
+	matches := statsRe.FindAllStringSubmatch(strings.ToLower(m.Content), -1)
+
+	target := matches[0][1]
+	if target == "" {
+		target = m.Author.ID
+	}
+
+	//Looking at someone else shows how you do against them
+	opponent := matches[0][2]
+	if opponent == "" && target != m.Author.ID {
+		opponent = m.Author.ID
 	}
 
-	playerTarget := matches[0][1]
-	pieceTarget := matches[0][2]
+	s.ChannelTyping(m.ChannelID)
 
-	game, err := getGame(m, playerTarget)
+	games, err := dbIns.GetPlayerArchivedGames(m.GuildID, target)
 	if err != nil {
-		printMissingGame(s, m)
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("error getting games! %v", err))
 		return
 	}
 
-	game.MakeMove(chess.Move{
-		From: game.FindEmptySqaure(),
-		To:   chess.StringToPostion(pieceTarget),
-	})
+	stats := chess.NewPlayerStats(target)
+	headToHead := chess.NewPlayerStats(target)
+	for _, game := range games {
+		stats.Add(game)
+		if opponent != "" && game.GetOpponent(target).ID == opponent {
+			headToHead.Add(game)
+		}
+	}
 
-	game.Turn = game.GetOpponent(m.Author.ID).Side
+	if stats.Games() == 0 {
+		s.ChannelMessageSend(
+			m.ChannelID,
+			fmt.Sprintf("%s hasn't finished any games in this server", username(s, target)),
+		)
+		return
+	}
 
-	dbIns.SaveGame(game)
+	openings := make([]string, 0, favouriteOpenings)
+	for _, opening := range stats.FavouriteOpenings(favouriteOpenings) {
+		openings = append(openings, fmt.Sprintf("%s (%d)", opening.Name, opening.Games))
+	}
 
 	msg := fmt.Sprintf(
-		"Match between <@!%s>: %s and <@!%s>: %s En Passant",
-		game.White.ID, game.White.Side.String(), game.Black.ID, game.Black.Side.String(),
+		"Stats for %s in this server\n"+
+			"Games: %d (%s)\n"+
+			"Average length: %.1f moves\n"+
+			"Favourite openings: %s",
+		username(s, target), stats.Games(), recordMsg(stats),
+		stats.AverageLength(), strings.Join(openings, ", "),
 	)
-	sendGame(s, m.ChannelID, msg, game)
+	if opponent != "" {
+		msg += fmt.Sprintf("\nAgainst %s: %s", username(s, opponent), recordMsg(headToHead))
+	}
+
+	s.ChannelMessageSend(m.ChannelID, msg)
 }
 
-func movePromotionCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
-	matches := promotionRe.FindAllStringSubmatch(strings.ToLower(m.Content), -1)
+//playerResult how the game went for the player
+func playerResult(game *chess.Game, playerID string) string {
+	switch {
+	case game.Result.Outcome == chess.OutcomeDraw:
+		return "Draw"
+	case game.Result.Outcome == chess.OutcomeAborted:
+		return "Aborted"
+	case !game.Over():
+		return "Unknown"
+	case game.GetSidePlayer(game.Result.Winner()).ID == playerID:
+		return "Won"
+	}
 
-	if matches == nil {
-		s.ChannelMessageSend(
-			m.ChannelID,
-			fmt.Sprintf(
-				"<@!%s> invalid promo move",
-				m.Author.ID,
-			),
+	return "Lost"
+}
+
+//historyMsg lists a page of a player's finished games, returns the message
+//and how many pages there are
+func historyMsg(s *discordgo.Session, page *db.HistoryPage) (string, int, error) {
+	total, err := dbIns.CountPlayerArchives(page.GuildID, page.PlayerID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	pages := int((total + historyPageSize - 1) / historyPageSize)
+	if total == 0 {
+		return fmt.Sprintf("%s hasn't finished any games in this server", username(s, page.PlayerID)), 0, nil
+	}
+
+	start := int64(page.Page * historyPageSize)
+	ids, err := dbIns.GetPlayerArchives(page.GuildID, page.PlayerID, start, start+historyPageSize-1)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(
+		&msg, "Games finished by %s (page %d of %d)\n",
+		username(s, page.PlayerID), page.Page+1, pages,
+	)
+	for _, id := range ids {
+		game, err := dbIns.GetArchive(id)
+		if err != nil {
+			continue
+		}
+
+		date := "unknown date"
+		if at, err := chess.ArchiveTime(id); err == nil {
+			date = at.Format("2006-01-02")
+		}
+
+		fmt.Fprintf(
+			&msg, "`%s` %s %s vs %s",
+			id, date, playerResult(game, page.PlayerID),
+			username(s, game.GetOpponent(page.PlayerID).ID),
 		)
-		return
+		if game.Result.Reason != chess.ReasonNone {
+			fmt.Fprintf(&msg, " by %s", game.Result.Reason)
+		}
+		msg.WriteString("\n")
 	}
+	msg.WriteString("Use `replay ARCHIVE_ID` to see a game")
+
+	return msg.String(), pages, nil
+}
+
+func historyCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
+	matches := historyRe.FindAllStringSubmatch(strings.ToLower(m.Content), -1)
 
 	target := matches[0][1]
-	from := matches[0][2]
-	to := matches[0][3]
-	promo := matches[0][4]
-
-	var promotion chess.PieceType
-
-	switch promo {
-	case "rook":
-		promotion = chess.PieceTypeRook
-		break
-	case "knight":
-		promotion = chess.PieceTypeKnight
-		break
-	case "queen":
-		promotion = chess.PieceTypeQueen
-		break
-	case "bishop":
-		promotion = chess.PieceTypeBishop
-		break
-	default:
-		s.ChannelMessageSend(
-			m.ChannelID,
-			fmt.Sprintf(
-				"<@!%s> invalid promotion type",
-				m.Author.ID,
-			),
-		)
-		return
+	if target == "" {
+		target = m.Author.ID
 	}
 
-	game, err := getGame(m, target)
+	page := &db.HistoryPage{GuildID: m.GuildID, PlayerID: target}
+	msg, pages, err := historyMsg(s, page)
 	if err != nil {
-		printMissingGame(s, m)
+		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("error getting history! %v", err))
 		return
 	}
 
-	game.MakeMove(chess.Move{
-		From:      chess.StringToPostion(from),
-		To:        chess.StringToPostion(to),
-		Promotion: promotion,
-	})
-	dbIns.SaveGame(game)
+	if pages <= 1 {
+		s.ChannelMessageSend(m.ChannelID, msg)
+		return
+	}
 
-	msg := fmt.Sprintf(
-		"Match between <@!%s>: %s and <@!%s>: %s En Passant",
-		game.White.ID, game.White.Side.String(), game.Black.ID, game.Black.Side.String(),
+	sent, err := s.ChannelMessageSendComplex(
+		m.ChannelID,
+		&discordgo.MessageSend{Content: msg, Components: historyButtons(page.Page, pages)},
 	)
-	sendGame(s, m.ChannelID, msg, game)
+	if err != nil {
+		return
+	}
+	dbIns.SaveHistoryPage(sent.ID, page, historyExpiry)
+}
 
+//historyButtons the buttons that turn the page of a history message
+func historyButtons(page, pages int) []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				Label: "Newer", Style: discordgo.SecondaryButton, CustomID: previousPageID,
+				Emoji: discordgo.ComponentEmoji{Name: "⬅️"}, Disabled: page <= 0,
+			},
+			discordgo.Button{
+				Label: "Older", Style: discordgo.SecondaryButton, CustomID: nextPageID,
+				Emoji: discordgo.ComponentEmoji{Name: "➡️"}, Disabled: page >= pages-1,
+			},
+		}},
+	}
 }
 
-func resginCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
-	matches := resginRe.FindAllStringSubmatch(strings.ToLower(m.Content), -1)
+//respondPrivately answers an interaction with a message only the user sees
+func respondPrivately(s *discordgo.Session, i *discordgo.InteractionCreate, msg string) {
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: msg,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
 
-	target := matches[0][1]
+//historyButtonPress turns the page of a history message
+func historyButtonPress(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionMessageComponent {
+		return
+	}
+
+	step := 0
+	switch i.MessageComponentData().CustomID {
+	case previousPageID:
+		step = -1
+	case nextPageID:
+		step = 1
+	default:
+		return
+	}
+
+	page, err := dbIns.GetHistoryPage(i.Message.ID)
+	if err != nil {
+		respondPrivately(s, i, "This history has expired, use `history` to see it again")
+		return
+	}
 
-	game, err := getGame(m, target)
+	total, err := dbIns.CountPlayerArchives(page.GuildID, page.PlayerID)
 	if err != nil {
-		printMissingGame(s, m)
+		respondPrivately(s, i, fmt.Sprintf("error getting history! %v", err))
 		return
 	}
 
-	game.Winner = game.GetOpponent(m.Author.ID).Side
+	pages := int((total + historyPageSize - 1) / historyPageSize)
+	next := page.Page + step
+	if next >= pages {
+		next = pages - 1
+	}
+	if next < 0 {
+		next = 0
+	}
+	page.Page = next
 
-	err = dbIns.DeleteGame(game)
+	msg, pages, err := historyMsg(s, page)
 	if err != nil {
+		respondPrivately(s, i, fmt.Sprintf("error getting history! %v", err))
+		return
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content: msg, Components: historyButtons(page.Page, pages),
+		},
+	})
+	dbIns.SaveHistoryPage(i.Message.ID, page, historyExpiry)
+}
+
+func replayCmd(s *discordgo.Session, m *discordgo.MessageCreate) {
+	matches := replayRe.FindAllStringSubmatch(strings.ToLower(m.Content), -1)
+
+	id := matches[0][1]
+
+	var game *chess.Game
+	var err error
+	switch {
+	case archiveIDRe.MatchString(id):
+		game, err = dbIns.GetArchive(id)
+	case len(id) == 8:
+		game, err = dbIns.GetArchivedGame(id)
+	default:
+		err = errMissingGame
+	}
+
+	if err != nil || game.GuildID != m.GuildID {
 		s.ChannelMessageSend(
 			m.ChannelID,
-			fmt.Sprintf("<@!%s>: error deleting game! %v", m.Author.ID, err),
+			fmt.Sprintf("<@!%s>: There is no finished game `%s` in this server", m.Author.ID, id),
 		)
+		return
 	}
-	go dbIns.ArchiveGame(game)
 
 	msg := fmt.Sprintf(
-		"Match between <@!%s>: %s and <@!%s>: %s Final State\n"+
-			"🎉Winner🎉 <@!%s>",
-		game.White.ID, game.White.Side.String(), game.Black.ID, game.Black.Side.String(),
-		game.GetOpponent(m.Author.ID).ID,
+		"Replay of %s: %s vs %s: %s %s",
+		username(s, game.White.ID), game.White.Side.String(),
+		username(s, game.Black.ID), game.Black.Side.String(), game.Result,
 	)
+	if game.Result.Reason != chess.ReasonNone {
+		msg += fmt.Sprintf(" by %s", game.Result.Reason)
+	}
+
 	s.ChannelMessageSendComplex(
 		m.ChannelID,
+		&discordgo.MessageSend{
+			Content: msg,
+			Files: []*discordgo.File{
+				{
+					Name: fmt.Sprintf("%s.gif", game.ID()), ContentType: "gif",
+					Reader: game.CreateGif(),
+				},
+				{
+					Name: fmt.Sprintf("%s.pgn", game.ID()), ContentType: "application/vnd.chess-pgn",
+					Reader: strings.NewReader(game.PGN(pgnHeader(s, game))),
+				},
+			},
+		},
+	)
+}
+
+//endGame removes a finished game, archives it and posts the final gif
+func endGame(s *discordgo.Session, channelID string, game *chess.Game, msg string) {
+	//A game moved in or ended elsewhere since it was loaded is left alone
+	removed, err := dbIns.DeleteGame(game)
+	if err == db.ErrGameChanged {
+		log.Printf("game %s changed before it could end", game.ID())
+		return
+	} else if err != nil {
+		s.ChannelMessageSend(
+			channelID,
+			fmt.Sprintf("error deleting game `%s`! %v", game.ID(), err),
+		)
+		return
+	}
+
+	//Only whoever removed the game rates and archives it
+	if !removed {
+		log.Printf("game %s was already ended", game.ID())
+		return
+	}
+
+	ratings, err := updateRatings(game)
+	if err != nil {
+		msg += fmt.Sprintf("\nerror updating ratings! %v", err)
+	} else if ratings != "" {
+		msg += "\n" + ratings
+	}
+
+	if err := dbIns.ArchiveGame(game); err != nil {
+		msg += fmt.Sprintf("\nerror archiving game! %v", err)
+	}
+
+	s.ChannelMessageSendComplex(
+		channelID,
 		&discordgo.MessageSend{
 			Content: msg,
 			Files: []*discordgo.File{{
@@ -560,6 +2243,22 @@ func main() {
 	dbIns = &db.Instance{}
 	dbIns.Connect()
 
+	count, err := dbIns.MigrateGames()
+	if err != nil {
+		log.Printf("unable to migrate saved games %v", err)
+	} else if count > 0 {
+		fmt.Printf("Migrated %d saved games\n", count)
+	}
+
+	go func() {
+		count, err := dbIns.ReindexArchives()
+		if err != nil {
+			log.Printf("unable to index archived games %v", err)
+		} else if count > 0 {
+			fmt.Printf("Indexed %d archived games\n", count)
+		}
+	}()
+
 	token := strings.Replace(os.Getenv("DISCORD_AUTH"), "\"", "", -1)
 	discord, err := discordgo.New("Bot " + token)
 	if err != nil {
@@ -570,6 +2269,10 @@ func main() {
 	// Register the messageCreate func as a callback for MessageCreate events.
 	discord.AddHandler(commandSet.Handler)
 	discord.AddHandler(messageCreate)
+	discord.AddHandler(historyButtonPress)
+
+	//Commands are read from the message text
+	discord.Identify.Intents = discordgo.IntentsAllWithoutPrivileged | discordgo.IntentsMessageContent
 
 	// Open a websocket connection to Discord and begin listening.
 	err = discord.Open()
@@ -578,7 +2281,9 @@ func main() {
 		return
 	}
 
-	discord.UpdateStatus(-1, "\"-cb help\"")
+	discord.UpdateGameStatus(0, "\"-cb help\"")
+
+	go sweepGames(discord)
 
 	// Wait here until CTRL-C or other term signal is received.
 	fmt.Println("Bot is now running.  Press CTRL-C to exit.")
@@ -0,0 +1,181 @@
+package rating
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	//DefaultRating the rating new players start at
+	DefaultRating = 1500
+	//DefaultDeviation how unsure a new player's rating is, also the most it can be
+	DefaultDeviation = 350
+	//DefaultVolatility how much a new player's rating is expected to move
+	DefaultVolatility = 0.06
+	//ProvisionalDeviation ratings less certain than this are shown as provisional
+	ProvisionalDeviation = 110
+	//Period how long a rating period lasts, deviations grow each period a
+	//player doesn't play
+	Period = 30 * 24 * time.Hour
+
+	//scale converts between the Glicko and Glicko-2 scales
+	scale = 173.7178
+	//tau limits how quickly volatility changes
+	tau = 0.5
+	//epsilon when the volatility is close enough
+	epsilon = 0.000001
+)
+
+const (
+	//Loss the score for losing a game
+	Loss = 0
+	//Draw the score for drawing a game
+	Draw = 0.5
+	//Win the score for winning a game
+	Win = 1
+)
+
+//Rating a player's Glicko-2 rating
+type Rating struct {
+	Rating     float64 `json:"rating"`
+	Deviation  float64 `json:"deviation"`
+	Volatility float64 `json:"volatility"`
+	Games      int     `json:"games"`
+	//Change how much the rating moved in the last update
+	Change  float64   `json:"change"`
+	Updated time.Time `json:"updated"`
+}
+
+//Result a game against an opponent, the score is Win, Draw or Loss
+type Result struct {
+	Opponent Rating
+	Score    float64
+}
+
+//New the rating every player starts with
+func New() Rating {
+	return Rating{
+		Rating:     DefaultRating,
+		Deviation:  DefaultDeviation,
+		Volatility: DefaultVolatility,
+	}
+}
+
+//Provisional true when there aren't enough games to trust the rating
+func (r Rating) Provisional() bool {
+	return r.Deviation > ProvisionalDeviation
+}
+
+func (r Rating) String() string {
+	if r.Provisional() {
+		return fmt.Sprintf("%.0f?", r.Rating)
+	}
+
+	return fmt.Sprintf("%.0f", r.Rating)
+}
+
+//mu the rating on the Glicko-2 scale
+func (r Rating) mu() float64 {
+	return (r.Rating - DefaultRating) / scale
+}
+
+//phi the deviation on the Glicko-2 scale
+func (r Rating) phi() float64 {
+	return r.Deviation / scale
+}
+
+//Decay grows the deviation for every rating period since the last update
+func (r Rating) Decay(now time.Time) Rating {
+	if r.Updated.IsZero() {
+		return r
+	}
+
+	periods := int(now.Sub(r.Updated) / Period)
+	for i := 0; i < periods; i++ {
+		phi := math.Sqrt(r.phi()*r.phi() + r.Volatility*r.Volatility)
+		r.Deviation = math.Min(phi*scale, DefaultDeviation)
+	}
+	r.Updated = r.Updated.Add(time.Duration(periods) * Period)
+
+	return r
+}
+
+func g(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
+
+func expected(mu, muJ, phiJ float64) float64 {
+	return 1 / (1 + math.Exp(-g(phiJ)*(mu-muJ)))
+}
+
+//volatility finds the new volatility with the Illinois algorithm
+func (r Rating) volatility(delta, v float64) float64 {
+	phi := r.phi()
+	a := math.Log(r.Volatility * r.Volatility)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		return ex*(delta*delta-phi*phi-v-ex)/(2*math.Pow(phi*phi+v+ex, 2)) - (x-a)/(tau*tau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*tau) < 0 {
+			k++
+		}
+		B = a - k*tau
+	}
+
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > epsilon {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+
+	return math.Exp(A / 2)
+}
+
+//Update rates the games played in one rating period, the opponents
+//ratings should be from before the games
+func (r Rating) Update(now time.Time, results ...Result) Rating {
+	r = r.Decay(now)
+	before := r.Rating
+
+	mu, phi := r.mu(), r.phi()
+
+	var vInv, improvement float64
+	for _, result := range results {
+		muJ, phiJ := result.Opponent.mu(), result.Opponent.phi()
+		e := expected(mu, muJ, phiJ)
+		vInv += g(phiJ) * g(phiJ) * e * (1 - e)
+		improvement += g(phiJ) * (result.Score - e)
+	}
+
+	if len(results) > 0 {
+		v := 1 / vInv
+		sigma := r.volatility(v*improvement, v)
+		phiStar := math.Sqrt(phi*phi + sigma*sigma)
+		phi = 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+		mu += phi * phi * improvement
+
+		r.Rating = mu*scale + DefaultRating
+		r.Deviation = math.Min(phi*scale, DefaultDeviation)
+		r.Volatility = sigma
+		r.Games += len(results)
+	}
+
+	r.Change = r.Rating - before
+	r.Updated = now
+
+	return r
+}
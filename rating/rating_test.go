@@ -0,0 +1,73 @@
+package rating
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func near(a, b, within float64) bool {
+	return math.Abs(a-b) <= within
+}
+
+//TestGlickmanExample the worked example from Glickman's Glicko-2 paper
+func TestGlickmanExample(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	player := Rating{Rating: 1500, Deviation: 200, Volatility: 0.06}
+
+	got := player.Update(
+		now,
+		Result{Opponent: Rating{Rating: 1400, Deviation: 30}, Score: Win},
+		Result{Opponent: Rating{Rating: 1550, Deviation: 100}, Score: Loss},
+		Result{Opponent: Rating{Rating: 1700, Deviation: 300}, Score: Loss},
+	)
+
+	if !near(got.Rating, 1464.06, 0.01) || !near(got.Deviation, 151.52, 0.01) || !near(got.Volatility, 0.05999, 0.00001) {
+		t.Errorf("got %.2f %.2f %.5f want 1464.06 151.52 0.05999", got.Rating, got.Deviation, got.Volatility)
+	}
+	if !near(got.Change, -35.94, 0.01) || got.Games != 3 || !got.Updated.Equal(now) {
+		t.Errorf("change %.2f games %d updated %v", got.Change, got.Games, got.Updated)
+	}
+}
+
+func TestUpdateSingleGame(t *testing.T) {
+	now := time.Now()
+	white, black := New(), New()
+
+	newWhite := white.Update(now, Result{Opponent: black, Score: Win})
+	newBlack := black.Update(now, Result{Opponent: white, Score: Loss})
+
+	if newWhite.Change <= 0 || newBlack.Change >= 0 || !near(newWhite.Change, -newBlack.Change, 0.001) {
+		t.Errorf("white %+.2f black %+.2f", newWhite.Change, newBlack.Change)
+	}
+	if newWhite.Deviation >= DefaultDeviation || !newWhite.Provisional() {
+		t.Errorf("deviation %.2f after one game", newWhite.Deviation)
+	}
+
+	drawn := white.Update(now, Result{Opponent: black, Score: Draw})
+	if !near(drawn.Rating, DefaultRating, 0.001) {
+		t.Errorf("draw between equals moved the rating to %.2f", drawn.Rating)
+	}
+}
+
+func TestDecay(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := Rating{Rating: 1800, Deviation: 60, Volatility: 0.06, Updated: start}
+
+	if got := r.Decay(start.Add(Period / 2)); got != r {
+		t.Errorf("decayed within a period %+v", got)
+	}
+
+	once := r.Decay(start.Add(Period + time.Hour))
+	if once.Deviation <= r.Deviation || !once.Updated.Equal(start.Add(Period)) {
+		t.Errorf("after a period deviation %.2f updated %v", once.Deviation, once.Updated)
+	}
+	if again := once.Decay(start.Add(Period + 2*time.Hour)); again != once {
+		t.Error("decaying twice in a period grew the deviation again")
+	}
+
+	r.Deviation = DefaultDeviation - 1
+	if long := r.Decay(start.Add(10 * Period)); long.Deviation != DefaultDeviation {
+		t.Errorf("deviation grew past the default to %.2f", long.Deviation)
+	}
+}